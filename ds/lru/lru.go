@@ -27,23 +27,24 @@ func New[K comparable, V any](cap int) *Cache[K, V] {
 	}
 }
 
-func (c *Cache[K, V]) len() int {
+func (c *Cache[K, V]) Len() int {
 	c.RLock()
 	defer c.RUnlock()
 	return c.list.Len()
 }
 
-func (c *Cache[K, V]) clear() {
+func (c *Cache[K, V]) Clear() {
 	c.Lock()
 	defer c.Unlock()
 	c.list.Init()
 	clear(c.dict)
 }
 
-func (c *Cache[K, V]) put(key K, value V) {
+func (c *Cache[K, V]) Put(key K, value V) {
 	c.Lock()
 	defer c.Unlock()
 	if e, ok := c.dict[key]; ok {
+		e.Value.(*element[K, V]).value = value
 		c.list.MoveToFront(e)
 	} else {
 		if c.list.Len() == c.cap {
@@ -53,7 +54,7 @@ func (c *Cache[K, V]) put(key K, value V) {
 	}
 }
 
-func (c *Cache[K, V]) get(key K) (value V, ok bool) {
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
 	c.Lock()
 	defer c.Unlock()
 	if e, exist := c.dict[key]; exist {
@@ -64,7 +65,7 @@ func (c *Cache[K, V]) get(key K) (value V, ok bool) {
 	return value, false
 }
 
-func (c *Cache[K, V]) delete(key K) {
+func (c *Cache[K, V]) Delete(key K) {
 	c.Lock()
 	defer c.Unlock()
 	if e, ok := c.dict[key]; ok {
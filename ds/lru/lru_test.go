@@ -14,36 +14,36 @@ func TestNew(t *testing.T) {
 
 func TestPutAndLen(t *testing.T) {
 	cache := New[string, int](3)
-	cache.put("hello", 1)
-	cache.put("world", 2)
-	assert.Equal(t, 2, cache.len())
+	cache.Put("hello", 1)
+	cache.Put("world", 2)
+	assert.Equal(t, 2, cache.Len())
 }
 
 func TestPutAndGet(t *testing.T) {
 	cache := New[string, int](3)
 	values := [3]string{"a", "b", "c"}
 	for i, v := range values {
-		cache.put(v, i)
+		cache.Put(v, i)
 	}
-	assert.Equal(t, 3, cache.len())
-	cache.put("d", 4)
-	assert.Equal(t, 3, cache.len())
-	value, ok := cache.get("a")
+	assert.Equal(t, 3, cache.Len())
+	cache.Put("d", 4)
+	assert.Equal(t, 3, cache.Len())
+	value, ok := cache.Get("a")
 	assert.Equal(t, 0, value)
 	assert.False(t, ok)
-	cache.get("c")
-	cache.put("e", 5)
-	assert.Equal(t, 3, cache.len())
-	value, ok = cache.get("b")
+	cache.Get("c")
+	cache.Put("e", 5)
+	assert.Equal(t, 3, cache.Len())
+	value, ok = cache.Get("b")
 	assert.Equal(t, 0, value)
 	assert.False(t, ok)
-	cache.get("d")
-	cache.get("e")
-	cache.put("f", 6)
-	value, ok = cache.get("e")
+	cache.Get("d")
+	cache.Get("e")
+	cache.Put("f", 6)
+	value, ok = cache.Get("e")
 	assert.Equal(t, 5, value)
 	assert.True(t, ok)
-	value, ok = cache.get("c")
+	value, ok = cache.Get("c")
 	assert.Equal(t, 0, value)
 	assert.False(t, ok)
 }
@@ -52,12 +52,22 @@ func TestDelete(t *testing.T) {
 	cache := New[string, int](3)
 	values := [3]string{"a", "b", "c"}
 	for i, v := range values {
-		cache.put(v, i)
+		cache.Put(v, i)
 	}
-	assert.Equal(t, 3, cache.len())
-	cache.delete("c")
-	assert.Equal(t, 2, cache.len())
-	value, ok := cache.get("c")
+	assert.Equal(t, 3, cache.Len())
+	cache.Delete("c")
+	assert.Equal(t, 2, cache.Len())
+	value, ok := cache.Get("c")
 	assert.Equal(t, 0, value)
 	assert.False(t, ok)
 }
+
+func TestPutExisting(t *testing.T) {
+	cache := New[string, int](2)
+	cache.Put("a", 1)
+	cache.Put("a", 2)
+	assert.Equal(t, 1, cache.Len())
+	value, ok := cache.Get("a")
+	assert.Equal(t, 2, value)
+	assert.True(t, ok)
+}
@@ -19,6 +19,10 @@ func AddWriters(ws ...Writer) {
 	_log.WithWriters(ws...)
 }
 
+func WithMeta(meta map[string]any) Logger {
+	return _log.WithMeta(meta)
+}
+
 func Fatal(s string) {
 	_log.Fatal(s)
 }
@@ -1,5 +1,17 @@
 package log
 
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	TimeLayout = "2006-01-02T15:04:05.000Z07:00"
+)
+
 type (
 	Formatter interface {
 		Format(*Entry) string
@@ -12,3 +24,43 @@ type (
 		inline bool
 	}
 )
+
+func sortedKeys(meta map[string]any) (keys []string) {
+	keys = make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return
+}
+
+// Format entry as one line: time, level, message then the meta sorted by key.
+func (f *CLFormatter) Format(e *Entry) string {
+	s := strings.Builder{}
+	s.WriteString(fmt.Sprintf("%s %-5s %s", e.now.Format(TimeLayout), e.level.Name(), e.msg))
+	for _, k := range sortedKeys(e.meta) {
+		s.WriteString(fmt.Sprintf(" %s=%v", k, e.meta[k]))
+	}
+	return s.String()
+}
+
+// Format entry as JSON object, the meta is merged in the object if inline, otherwise kept under "meta".
+func (f *JsonFormatter) Format(e *Entry) string {
+	m := map[string]any{"time": e.now.Format(time.RFC3339Nano), "level": e.level, "msg": e.msg}
+	if len(e.meta) > 0 {
+		if f.inline {
+			for k, v := range e.meta {
+				if _, ok := m[k]; !ok {
+					m[k] = v
+				}
+			}
+		} else {
+			m["meta"] = e.meta
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Sprintf(`{"level":"ERROR","msg":%q}`, err.Error())
+	}
+	return string(b)
+}
@@ -43,6 +43,12 @@ type (
 		writers   []Writer
 		formatter Formatter
 	}
+
+	// metaLog attaches the same meta to every entry it logs.
+	metaLog struct {
+		log  *Log
+		meta map[string]any
+	}
 )
 
 func newEntry(l Level, m string) (e *Entry) {
@@ -66,7 +72,7 @@ func (e *Entry) withMeta(k string, v any) *Entry {
 }
 
 func New() (l *Log) {
-	l = &Log{msgChan: make(chan *Entry, MsgChanCap), writers: []Writer{}}
+	l = &Log{msgChan: make(chan *Entry, MsgChanCap), writers: []Writer{}, formatter: &CLFormatter{}}
 	return l
 }
 
@@ -155,3 +161,63 @@ func (l *Log) Trace(s string) {
 func (l *Log) Tracef(s string, a any) {
 	l.msgChan <- newEntry(TRACE, fmt.Sprintf(s, a))
 }
+
+func (l *Log) WithMeta(meta map[string]any) Logger {
+	return &metaLog{log: l, meta: meta}
+}
+
+func (m *metaLog) entry(l Level, s string) (e *Entry) {
+	e = newEntry(l, s)
+	for k, v := range m.meta {
+		e.withMeta(k, v)
+	}
+	return
+}
+
+func (m *metaLog) Fatal(s string) {
+	m.log.msgChan <- m.entry(FATAL, s)
+}
+
+func (m *metaLog) Fatalf(s string, a any) {
+	m.log.msgChan <- m.entry(FATAL, fmt.Sprintf(s, a))
+}
+
+func (m *metaLog) Error(s string) {
+	m.log.msgChan <- m.entry(ERROR, s)
+}
+
+func (m *metaLog) Errorf(s string, a any) {
+	m.log.msgChan <- m.entry(ERROR, fmt.Sprintf(s, a))
+}
+
+func (m *metaLog) Warn(s string) {
+	m.log.msgChan <- m.entry(WARN, s)
+}
+
+func (m *metaLog) Warnf(s string, a any) {
+	m.log.msgChan <- m.entry(WARN, fmt.Sprintf(s, a))
+}
+
+func (m *metaLog) Info(s string) {
+	m.log.msgChan <- m.entry(INFO, s)
+}
+
+func (m *metaLog) Infof(s string, a any) {
+	m.log.msgChan <- m.entry(INFO, fmt.Sprintf(s, a))
+}
+
+func (m *metaLog) Debug(s string) {
+	m.log.msgChan <- m.entry(DEBUG, s)
+}
+
+func (m *metaLog) Debugf(s string, a any) {
+	m.log.msgChan <- m.entry(DEBUG, fmt.Sprintf(s, a))
+}
+
+func (m *metaLog) Trace(s string) {
+	m.log.msgChan <- m.entry(TRACE, s)
+}
+
+func (m *metaLog) Tracef(s string, a any) {
+	m.log.msgChan <- m.entry(TRACE, fmt.Sprintf(s, a))
+}
@@ -1 +1,25 @@
 package log
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLogWithMeta(t *testing.T) {
+	l := New()
+	l.WithMeta(map[string]any{"request_id": "abc"}).Infof("hello %s", "world")
+	e := <-l.msgChan
+	assert.Equal(t, INFO, e.level)
+	assert.Equal(t, "hello world", e.msg)
+	assert.Equal(t, map[string]any{"request_id": "abc"}, e.meta)
+}
+
+func TestFormatters(t *testing.T) {
+	e := newEntry(WARN, "hello")
+	e.now = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	e.withMeta("route", "/a").withMeta("method", "GET")
+	assert.Equal(t, "2024-01-02T03:04:05.000Z WARN  hello method=GET route=/a", (&CLFormatter{}).Format(e))
+	assert.JSONEq(t, `{"time":"2024-01-02T03:04:05Z","level":"WARN","msg":"hello","meta":{"method":"GET","route":"/a"}}`, (&JsonFormatter{}).Format(e))
+	assert.JSONEq(t, `{"time":"2024-01-02T03:04:05Z","level":"WARN","msg":"hello","method":"GET","route":"/a"}`, (&JsonFormatter{inline: true}).Format(e))
+}
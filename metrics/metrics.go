@@ -0,0 +1,378 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentType of the Prometheus text exposition format written by Registry.WriteTo.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// DefaultBuckets are the upper bounds of the histograms in seconds, suited to the latency of HTTP requests.
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	nameRe  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+type (
+	// Registry holds the metrics, and writes them in the order of their registration.
+	Registry struct {
+		collectors []collector
+		names      map[string]bool
+		mutex      sync.RWMutex
+	}
+
+	collector interface {
+		write(w *bufio.Writer)
+	}
+
+	desc struct {
+		name   string
+		help   string
+		typ    string
+		labels []string
+	}
+
+	// vec holds the metric of every combination of label values.
+	vec[M any] struct {
+		desc
+		newMetric func() *M
+		children  map[string]*child[M]
+		mutex     sync.RWMutex
+	}
+
+	child[M any] struct {
+		values []string
+		metric *M
+	}
+
+	CounterVec struct {
+		*vec[Counter]
+	}
+
+	GaugeVec struct {
+		*vec[Gauge]
+	}
+
+	HistogramVec struct {
+		*vec[Histogram]
+	}
+
+	// Counter only increases, its value is a float64 stored in the bits of an atomic.
+	Counter struct {
+		bits atomic.Uint64
+	}
+
+	Gauge struct {
+		bits atomic.Uint64
+	}
+
+	// Histogram counts the observations in buckets of cumulative upper bounds.
+	Histogram struct {
+		buckets []float64
+		counts  []atomic.Uint64
+		count   atomic.Uint64
+		sum     Gauge
+	}
+
+	funcMetric struct {
+		desc
+		fn func() float64
+	}
+)
+
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+func (r *Registry) register(d desc, c collector) {
+	if !nameRe.MatchString(d.name) {
+		panic(fmt.Sprintf("Invalid metric name %q!", d.name))
+	}
+	for _, label := range d.labels {
+		if !labelRe.MatchString(label) || strings.HasPrefix(label, "__") || label == "le" {
+			panic(fmt.Sprintf("Invalid label name %q of metric %s!", label, d.name))
+		}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.names[d.name] {
+		panic(fmt.Sprintf("Duplicated metric name %s!", d.name))
+	}
+	r.names[d.name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+func newVec[M any](d desc, newMetric func() *M) *vec[M] {
+	return &vec[M]{desc: d, newMetric: newMetric, children: map[string]*child[M]{}}
+}
+
+// WithLabelValues returns the metric of the label values, given in the order of the label names, creating it if needed.
+func (v *vec[M]) WithLabelValues(values ...string) *M {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("Metric %s expects %d label values, got %d!", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mutex.RLock()
+	c, ok := v.children[key]
+	v.mutex.RUnlock()
+	if ok {
+		return c.metric
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if c, ok = v.children[key]; !ok {
+		c = &child[M]{values: slices.Clone(values), metric: v.newMetric()}
+		v.children[key] = c
+	}
+	return c.metric
+}
+
+// sorted returns the children by label values, so that the output is stable.
+func (v *vec[M]) sorted() (children []*child[M]) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	children = make([]*child[M], 0, len(v.children))
+	for _, c := range v.children {
+		children = append(children, c)
+	}
+	slices.SortFunc(children, func(a, b *child[M]) int {
+		return slices.Compare(a.values, b.values)
+	})
+	return
+}
+
+func (r *Registry) NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	v := &CounterVec{newVec(desc{name: name, help: help, typ: "counter", labels: labels}, func() *Counter {
+		return &Counter{}
+	})}
+	r.register(v.desc, v)
+	return v
+}
+
+func (r *Registry) NewCounter(name string, help string) *Counter {
+	return r.NewCounterVec(name, help).WithLabelValues()
+}
+
+func (r *Registry) NewGaugeVec(name string, help string, labels ...string) *GaugeVec {
+	v := &GaugeVec{newVec(desc{name: name, help: help, typ: "gauge", labels: labels}, func() *Gauge {
+		return &Gauge{}
+	})}
+	r.register(v.desc, v)
+	return v
+}
+
+func (r *Registry) NewGauge(name string, help string) *Gauge {
+	return r.NewGaugeVec(name, help).WithLabelValues()
+}
+
+// NewHistogramVec creates the histograms with the sorted upper bounds of the buckets, DefaultBuckets if empty.
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("Buckets of histogram %s should be sorted!", name))
+	}
+	buckets = slices.Clone(buckets)
+	v := &HistogramVec{newVec(desc{name: name, help: help, typ: "histogram", labels: labels}, func() *Histogram {
+		return &Histogram{buckets: buckets, counts: make([]atomic.Uint64, len(buckets))}
+	})}
+	r.register(v.desc, v)
+	return v
+}
+
+func (r *Registry) NewHistogram(name string, help string, buckets []float64) *Histogram {
+	return r.NewHistogramVec(name, help, buckets).WithLabelValues()
+}
+
+// NewCounterFunc exposes a counter maintained elsewhere, fn is called on every collection.
+func (r *Registry) NewCounterFunc(name string, help string, fn func() float64) {
+	m := &funcMetric{desc: desc{name: name, help: help, typ: "counter"}, fn: fn}
+	r.register(m.desc, m)
+}
+
+// NewGaugeFunc exposes a gauge computed on every collection.
+func (r *Registry) NewGaugeFunc(name string, help string, fn func() float64) {
+	m := &funcMetric{desc: desc{name: name, help: help, typ: "gauge"}, fn: fn}
+	r.register(m.desc, m)
+}
+
+func addFloat(bits *atomic.Uint64, delta float64) {
+	for {
+		old := bits.Load()
+		if bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("Counter can not decrease!")
+	}
+	addFloat(&c.bits, delta)
+}
+
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+func (g *Gauge) Set(value float64) {
+	g.bits.Store(math.Float64bits(value))
+}
+
+func (g *Gauge) Inc() {
+	addFloat(&g.bits, 1)
+}
+
+func (g *Gauge) Dec() {
+	addFloat(&g.bits, -1)
+}
+
+func (g *Gauge) Add(delta float64) {
+	addFloat(&g.bits, delta)
+}
+
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+func (h *Histogram) Observe(value float64) {
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.buckets) {
+		h.counts[i].Add(1)
+	}
+	h.sum.Add(value)
+	h.count.Add(1)
+}
+
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+func (h *Histogram) Sum() float64 {
+	return h.sum.Value()
+}
+
+// WriteTo writes all the metrics in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (n int64, err error) {
+	r.mutex.RLock()
+	collectors := slices.Clone(r.collectors)
+	r.mutex.RUnlock()
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err = bw.Flush()
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(b []byte) (n int, err error) {
+	n, err = cw.w.Write(b)
+	cw.n += int64(n)
+	return
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	if d.help != "" {
+		w.WriteString("# HELP " + d.name + " " + helpReplacer.Replace(d.help) + "\n")
+	}
+	w.WriteString("# TYPE " + d.name + " " + d.typ + "\n")
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// writeSample writes a line of the metric, the extra label being the "le" bound of the histogram buckets.
+func writeSample(w *bufio.Writer, name string, labels []string, values []string, extra string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extra != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + labelReplacer.Replace(values[i]) + `"`)
+		}
+		if extra != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extra)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	for _, c := range v.sorted() {
+		writeSample(w, v.name, v.labels, c.values, "", c.metric.Value())
+	}
+}
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	for _, c := range v.sorted() {
+		writeSample(w, v.name, v.labels, c.values, "", c.metric.Value())
+	}
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	for _, c := range v.sorted() {
+		h := c.metric
+		// The count is loaded first, so that the +Inf bucket is never lower than the other ones during an observation.
+		count := h.Count()
+		cumulative := uint64(0)
+		for i, bound := range h.buckets {
+			cumulative += h.counts[i].Load()
+			writeSample(w, v.name+"_bucket", v.labels, c.values, `le="`+formatFloat(bound)+`"`, float64(min(cumulative, count)))
+		}
+		writeSample(w, v.name+"_bucket", v.labels, c.values, `le="+Inf"`, float64(count))
+		writeSample(w, v.name+"_sum", v.labels, c.values, "", h.Sum())
+		writeSample(w, v.name+"_count", v.labels, c.values, "", float64(count))
+	}
+}
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	m.writeHeader(w)
+	writeSample(w, m.name, nil, nil, "", m.fn())
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCounterAndGauge(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("jobs_total", "Processed jobs.")
+	c.Inc()
+	c.Add(1.5)
+	assert.Equal(t, 2.5, c.Value())
+	assert.Panics(t, func() { c.Add(-1) })
+
+	g := r.NewGauge("queue_length", "")
+	g.Set(3)
+	g.Inc()
+	g.Dec()
+	g.Dec()
+	g.Add(-0.5)
+	assert.Equal(t, 1.5, g.Value())
+}
+
+func TestRegisterPanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("jobs_total", "")
+	tcs := map[string]func(){
+		"duplicated":    func() { r.NewGauge("jobs_total", "") },
+		"invalid name":  func() { r.NewGauge("jobs-total", "") },
+		"invalid label": func() { r.NewGaugeVec("queue", "", "le") },
+		"reserved":      func() { r.NewGaugeVec("queue", "", "__name") },
+		"unsorted":      func() { r.NewHistogram("latency", "", []float64{1, 0.5}) },
+		"label count": func() {
+			r.NewCounterVec("requests_total", "", "method").WithLabelValues("GET", "POST")
+		},
+	}
+	for name, f := range tcs {
+		assert.Panics(t, f, name)
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	v := r.NewCounterVec("requests_total", "", "method")
+	h := r.NewHistogram("latency_seconds", "", nil)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				v.WithLabelValues("GET").Inc()
+				h.Observe(0.01)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 8000.0, v.WithLabelValues("GET").Value())
+	assert.Equal(t, uint64(8000), h.Count())
+	assert.InDelta(t, 80.0, h.Sum(), 1e-9)
+}
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("http_requests_total", "Requests by route.\nCounted once finished.", "route", "status")
+	requests.WithLabelValues("/users/{id}", "2xx").Add(3)
+	requests.WithLabelValues(`/a"b\c`, "5xx").Inc()
+	r.NewGaugeVec("http_requests_in_flight", "", "route")
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.1)
+	h.Observe(0.5)
+	h.Observe(5)
+	r.NewGaugeFunc("temperature", "", func() float64 { return math.Inf(1) })
+	r.NewCounterFunc("cache_hits_total", "Cache hits.", func() float64 { return 42 })
+
+	b := strings.Builder{}
+	n, err := r.WriteTo(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(b.Len()), n)
+	assert.Equal(t, `# HELP http_requests_total Requests by route.\nCounted once finished.
+# TYPE http_requests_total counter
+http_requests_total{route="/a\"b\\c",status="5xx"} 1
+http_requests_total{route="/users/{id}",status="2xx"} 3
+# TYPE http_requests_in_flight gauge
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 5.65
+latency_seconds_count 4
+# TYPE temperature gauge
+temperature +Inf
+# HELP cache_hits_total Cache hits.
+# TYPE cache_hits_total counter
+cache_hits_total 42
+`, b.String())
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// WrapHandler adapts a standard handler to the chain, it can read the values of Context.Set from the request context.
+func WrapHandler(h http.Handler) func(*Context) {
+	return func(c *Context) {
+		h.ServeHTTP(c.Writer, c.Req)
+	}
+}
+
+func WrapHandlerFunc(f http.HandlerFunc) func(*Context) {
+	return WrapHandler(f)
+}
+
+// WrapMiddleware adapts a standard middleware, its next handler runs the rest of the chain with the writer and the request
+// it passes. The chain is aborted if the middleware answers without calling next, which only runs once.
+// The errors of the handlers are rendered once the middleware has returned, outside of its writer.
+// As next may run on another goroutine, like under http.TimeoutHandler, the chain runs on a copy of the Context. Its state
+// is taken back if it has returned along with the middleware, otherwise the chain is aborted and the copy finishes on its own,
+// writing only to the writer of the middleware.
+func WrapMiddleware(m func(http.Handler) http.Handler) func(*Context) {
+	return func(c *Context) {
+		f := c.fork()
+		called := atomic.Bool{}
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if called.Swap(true) {
+				return
+			}
+			f.c.setWriter(w)
+			f.c.Req = r
+			f.run(func(fc *Context) {
+				fc.Next()
+			})
+		})
+		m(next).ServeHTTP(c.Writer, c.Req)
+		// a next called after the middleware has returned is ignored.
+		if !called.Swap(true) || !f.join() {
+			c.Abort()
+		}
+	}
+}
+
+// Mount serves the prefix and everything under it for every method by the handler, which receives the paths without the prefix.
+func (rg *RouterGroup) Mount(prefix string, h http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	fullPrefix := rg.getPrefix() + prefix
+	handler := func(c *Context) {
+		stripPrefix(fullPrefix, h).ServeHTTP(c.Writer, c.Req)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace} {
+		if prefix != "" {
+			rg.PutRoute(method, prefix, handler).Hide()
+		}
+		rg.PutRoute(method, prefix+"/", handler).Hide()
+		rg.PutRoute(method, prefix+"/{(?P<path>.+)}", handler).Hide()
+	}
+}
+
+func (s *Server) Mount(prefix string, h http.Handler) {
+	s.rg.Mount(prefix, h)
+}
+
+// stripPrefix is http.StripPrefix, except that the prefix itself is served as "/" rather than an empty path.
+func stripPrefix(prefix string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, prefix)
+		rp := strings.TrimPrefix(r.URL.RawPath, prefix)
+		if !strings.HasPrefix(p, "/") {
+			p, rp = "/"+p, "/"+rp
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = p
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath = rp
+		}
+		h.ServeHTTP(w, r2)
+	})
+}
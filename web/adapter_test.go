@@ -0,0 +1,169 @@
+package web
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ctxKey struct{}
+
+func TestWrapHandler(t *testing.T) {
+	s := New()
+	s.PreMiddlewares(func(c *Context) {
+		c.Set("user", "alice")
+	})
+	s.GET("/std", WrapHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello " + r.Context().Value("user").(string)))
+	}))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/std", nil))
+	assert.Equal(t, "hello alice", w.Body.String())
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	var order []string
+	trace := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "before")
+			w.Header().Set("X-Trace", "1")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, "traced")))
+			order = append(order, "after")
+		})
+	}
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "denied", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	s := New()
+	s.PreMiddlewares(WrapMiddleware(trace), WrapMiddleware(deny))
+	s.PostMiddlewares(func(c *Context) {
+		order = append(order, "post")
+	})
+	s.GET("/", func(c *Context) {
+		order = append(order, "handler")
+		c.String(http.StatusOK, "%v", c.Req.Context().Value(ctxKey{}))
+	})
+	s.GET("/fail", func(c *Context) {
+		c.Error(NewHTTPError(http.StatusConflict))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "traced", w.Body.String())
+	assert.Equal(t, "1", w.Header().Get("X-Trace"))
+	assert.Equal(t, []string{"before", "handler", "post", "after"}, order)
+
+	order = nil
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "denied\n", w.Body.String())
+	assert.Equal(t, []string{"before", "after"}, order)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestMount(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path))
+	})
+	s := New()
+	s.Mount("/legacy", mux)
+	api := s.Group("/api").PreMiddlewares(func(c *Context) {
+		c.SetHeader("X-Api", "1")
+	})
+	api.Mount("/v0/", mux)
+	s.GET("/legacyish", func(c *Context) {
+		c.String(http.StatusOK, "native")
+	})
+	tcs := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodGet, path: "/legacy", body: "GET /"},
+		{method: http.MethodGet, path: "/legacy/", body: "GET /"},
+		{method: http.MethodPost, path: "/legacy/items/1", body: "POST /items/1"},
+		{method: http.MethodDelete, path: "/api/v0/items", body: "DELETE /items"},
+		{method: http.MethodGet, path: "/legacyish", body: "native"},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, strings.NewReader("")))
+		assert.Equal(t, http.StatusOK, w.Code, tc.path)
+		assert.Equal(t, tc.body, w.Body.String(), tc.path)
+		assert.Equal(t, strings.HasPrefix(tc.path, "/api"), w.Header().Get("X-Api") == "1")
+	}
+}
+
+func TestWrapMiddlewarePanic(t *testing.T) {
+	// the middleware holds the response back in its own writer, which is lost with the panic.
+	buffer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, r)
+			w.WriteHeader(rec.Code)
+			_, _ = w.Write(rec.Body.Bytes())
+		})
+	}
+	s := New()
+	s.PreMiddlewares(WrapMiddleware(buffer))
+	s.GET("/panic", func(c *Context) { panic("boom") })
+	s.GET("/ok", func(c *Context) { c.String(http.StatusOK, "ok") })
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotEmpty(t, w.Body.String())
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestWrapMiddlewareTimeoutHandler(t *testing.T) {
+	release, finished := make(chan struct{}), make(chan error, 1)
+	var post []string
+	s := New()
+	s.PreMiddlewares(WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, 20*time.Millisecond, "too slow")
+	}))
+	s.PostMiddlewares(func(c *Context) { post = append(post, c.Path) })
+	s.GET("/fast", func(c *Context) { c.String(http.StatusOK, "fast") })
+	s.GET("/slow", func(c *Context) {
+		<-release
+		_, err := c.Writer.Write([]byte("late"))
+		finished <- err
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "fast", w.Body.String())
+	assert.Equal(t, []string{"/fast"}, post)
+
+	// the chain keeps running on its copy of the Context after the timeout response, its writes are refused.
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "too slow", w.Body.String())
+	close(release)
+	assert.ErrorIs(t, <-finished, http.ErrHandlerTimeout)
+}
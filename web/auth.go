@@ -0,0 +1,148 @@
+package web
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+const (
+	HeaderAuthorization   = "Authorization"
+	HeaderWWWAuthenticate = "WWW-Authenticate"
+	HeaderXAPIKey         = "X-API-Key"
+	AuthRealm             = "Restricted"
+	AuthSchemeBasic       = "Basic"
+	AuthSchemeBearer      = "Bearer"
+	AuthSchemeAPIKey      = "APIKey"
+)
+
+type (
+	// Principal is the authenticated identity placed on Context by the authentication middlewares.
+	Principal struct {
+		ID     string
+		Scheme string
+		Roles  []string
+		Scopes []string
+		Claims map[string]any
+	}
+
+	BasicAuthConfig struct {
+		Realm string
+		// Credentials returns the expected password and the principal of the user, ok is false if the user is unknown.
+		Credentials func(username string) (password string, principal *Principal, ok bool)
+	}
+
+	APIKeyConfig struct {
+		// Header carrying the key, X-API-Key by default.
+		Header string
+		// Query parameter carrying the key, checked when the header is missing, disabled if empty.
+		Query string
+		// Validator returns the principal owning the key, ok is false if the key is unknown.
+		Validator func(key string) (principal *Principal, ok bool)
+	}
+)
+
+func (c *Context) Principal() *Principal {
+	return c.principal
+}
+
+func (c *Context) SetPrincipal(p *Principal) {
+	c.principal = p
+}
+
+// Claims returns the claims of the authenticated principal, nil if there is none.
+func (c *Context) Claims() map[string]any {
+	if c.principal == nil {
+		return nil
+	}
+	return c.principal.Claims
+}
+
+// ConstantTimeEqual compares the digests of the strings, so that the duration does not leak the length nor the common prefix.
+func ConstantTimeEqual(a, b string) bool {
+	da, db := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(da[:], db[:]) == 1
+}
+
+// BasicAuth authenticates against static accounts, mapping the usernames to their passwords.
+func BasicAuth(accounts map[string]string) func(*Context) {
+	return BasicAuthWithConfig(BasicAuthConfig{
+		Credentials: func(username string) (password string, principal *Principal, ok bool) {
+			if password, ok = accounts[username]; ok {
+				principal = &Principal{ID: username}
+			}
+			return
+		},
+	})
+}
+
+func BasicAuthWithConfig(cfg BasicAuthConfig) func(*Context) {
+	if cfg.Credentials == nil {
+		panic("Basic auth credentials should not be nil!")
+	}
+	if cfg.Realm == "" {
+		cfg.Realm = AuthRealm
+	}
+	challenge := AuthSchemeBasic + ` realm="` + strings.ReplaceAll(cfg.Realm, `"`, `\"`) + `", charset="UTF-8"`
+	return func(c *Context) {
+		username, password, ok := c.Req.BasicAuth()
+		if ok {
+			expected, principal, found := cfg.Credentials(username)
+			// compare even for unknown users, so that the duration does not reveal which users exist.
+			if ConstantTimeEqual(password, expected) && found {
+				// the principal is copied, as the validators may share it between the requests.
+				p := Principal{ID: username}
+				if principal != nil {
+					p = *principal
+				}
+				p.Scheme = AuthSchemeBasic
+				c.SetPrincipal(&p)
+				return
+			}
+		}
+		c.SetHeader(HeaderWWWAuthenticate, challenge)
+		c.Error(NewHTTPError(http.StatusUnauthorized))
+	}
+}
+
+func APIKey(validator func(key string) (*Principal, bool)) func(*Context) {
+	return APIKeyWithConfig(APIKeyConfig{Validator: validator})
+}
+
+func APIKeyWithConfig(cfg APIKeyConfig) func(*Context) {
+	if cfg.Validator == nil {
+		panic("API key validator should not be nil!")
+	}
+	if cfg.Header == "" {
+		cfg.Header = HeaderXAPIKey
+	}
+	return func(c *Context) {
+		key := c.Req.Header.Get(cfg.Header)
+		if key == "" && cfg.Query != "" {
+			key = c.Query(cfg.Query)
+		}
+		if key != "" {
+			if principal, ok := cfg.Validator(key); ok {
+				p := Principal{}
+				if principal != nil {
+					p = *principal
+				}
+				p.Scheme = AuthSchemeAPIKey
+				c.SetPrincipal(&p)
+				return
+			}
+		}
+		c.Error(NewHTTPError(http.StatusUnauthorized))
+	}
+}
+
+// bearerToken extracts the token of the Authorization header, the scheme is case-insensitive.
+func bearerToken(c *Context) (token string, ok bool) {
+	scheme, token, ok := strings.Cut(c.Req.Header.Get(HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, AuthSchemeBearer) {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
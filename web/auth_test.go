@@ -0,0 +1,84 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConstantTimeEqual(t *testing.T) {
+	assert.True(t, ConstantTimeEqual("secret", "secret"))
+	assert.False(t, ConstantTimeEqual("secret", "secre"))
+	assert.False(t, ConstantTimeEqual("", "secret"))
+}
+
+func TestBasicAuth(t *testing.T) {
+	s := New()
+	g := s.Group("/admin").PreMiddlewares(BasicAuth(map[string]string{"alice": "wonderland"}))
+	g.GET("/", func(c *Context) { c.String(http.StatusOK, c.Principal().ID) })
+	tcs := []struct {
+		username string
+		password string
+		set      bool
+		code     int
+	}{
+		{username: "alice", password: "wonderland", set: true, code: http.StatusOK},
+		{username: "alice", password: "wrong", set: true, code: http.StatusUnauthorized},
+		{username: "bob", password: "wonderland", set: true, code: http.StatusUnauthorized},
+		{set: false, code: http.StatusUnauthorized},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/", nil)
+		if tc.set {
+			req.SetBasicAuth(tc.username, tc.password)
+		}
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code)
+		if tc.code == http.StatusOK {
+			assert.Equal(t, "alice", w.Body.String())
+		} else {
+			assert.Equal(t, `Basic realm="Restricted", charset="UTF-8"`, w.Header().Get(HeaderWWWAuthenticate))
+		}
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	// the validator shares the principal between the requests, it must not be modified.
+	service := &Principal{ID: "service"}
+	s := New()
+	g := s.Group("/api").PreMiddlewares(APIKeyWithConfig(APIKeyConfig{
+		Query: "api_key",
+		Validator: func(key string) (*Principal, bool) {
+			if key == "k1" {
+				return service, true
+			}
+			return nil, false
+		},
+	}))
+	g.GET("/", func(c *Context) { c.String(http.StatusOK, c.Principal().Scheme+":"+c.Principal().ID) })
+	tcs := []struct {
+		target string
+		header string
+		code   int
+	}{
+		{target: "/api/", header: "k1", code: http.StatusOK},
+		{target: "/api/?api_key=k1", code: http.StatusOK},
+		{target: "/api/", header: "k2", code: http.StatusUnauthorized},
+		{target: "/api/", code: http.StatusUnauthorized},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.header != "" {
+			req.Header.Set(HeaderXAPIKey, tc.header)
+		}
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code)
+		if tc.code == http.StatusOK {
+			assert.Equal(t, "APIKey:service", w.Body.String())
+		}
+	}
+	assert.Empty(t, service.Scheme)
+}
@@ -0,0 +1,104 @@
+package web
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Permission is granted to a principal having any of the roles and all the scopes, an empty one only requires authentication.
+type Permission struct {
+	Roles  []string
+	Scopes []string
+}
+
+func (p Permission) allows(principal *Principal) bool {
+	if len(p.Roles) > 0 && !slices.ContainsFunc(p.Roles, func(role string) bool {
+		return slices.Contains(principal.Roles, role)
+	}) {
+		return false
+	}
+	for _, scope := range p.Scopes {
+		if !slices.Contains(principal.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func (rg *RouterGroup) Require(permissions ...Permission) *RouterGroup {
+	rg.permissions = append(rg.permissions, permissions...)
+	rg.router.generation.Add(1)
+	return rg
+}
+
+func (rg *RouterGroup) RequireAuth() *RouterGroup {
+	return rg.Require(Permission{})
+}
+
+func (rg *RouterGroup) RequireRoles(roles ...string) *RouterGroup {
+	return rg.Require(Permission{Roles: roles})
+}
+
+func (rg *RouterGroup) RequireScopes(scopes ...string) *RouterGroup {
+	return rg.Require(Permission{Scopes: scopes})
+}
+
+func (r *Route) Require(permissions ...Permission) *Route {
+	r.permissions = append(r.permissions, permissions...)
+	r.chain.Store(nil)
+	return r
+}
+
+func (r *Route) RequireAuth() *Route {
+	return r.Require(Permission{})
+}
+
+func (r *Route) RequireRoles(roles ...string) *Route {
+	return r.Require(Permission{Roles: roles})
+}
+
+func (r *Route) RequireScopes(scopes ...string) *Route {
+	return r.Require(Permission{Scopes: scopes})
+}
+
+// getPermissions collects the permissions of the groups from the root, then the ones of the route, all of them must be granted.
+func (r *Route) getPermissions() (permissions []Permission) {
+	if r == nil {
+		return
+	}
+	for g := r.group; g != nil; g = g.parent {
+		permissions = slices.Concat(g.permissions, permissions)
+	}
+	return append(permissions, r.permissions...)
+}
+
+func (r *Route) Protected() bool {
+	return len(r.getPermissions()) > 0
+}
+
+// authorize answers 401 if no principal has been authenticated by the previous middlewares, 403 if a permission is not granted.
+func authorize(permissions []Permission) func(*Context) {
+	return func(c *Context) {
+		principal := c.Principal()
+		if principal == nil {
+			c.Error(NewHTTPError(http.StatusUnauthorized))
+			return
+		}
+		for _, p := range permissions {
+			if !p.allows(principal) {
+				c.Error(NewHTTPError(http.StatusForbidden))
+				return
+			}
+		}
+	}
+}
+
+// UnprotectedRoutes reports the routes reachable without any authenticated principal.
+func (s *Server) UnprotectedRoutes() (routes []*Route) {
+	for _, route := range s.Routes() {
+		if !route.Protected() {
+			routes = append(routes, route)
+		}
+	}
+	return
+}
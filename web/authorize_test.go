@@ -0,0 +1,92 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPermissionAllows(t *testing.T) {
+	principal := &Principal{Roles: []string{"editor"}, Scopes: []string{"read", "write"}}
+	tcs := []struct {
+		permission Permission
+		allowed    bool
+	}{
+		{permission: Permission{}, allowed: true},
+		{permission: Permission{Roles: []string{"admin", "editor"}}, allowed: true},
+		{permission: Permission{Roles: []string{"admin"}}, allowed: false},
+		{permission: Permission{Scopes: []string{"read", "write"}}, allowed: true},
+		{permission: Permission{Scopes: []string{"read", "delete"}}, allowed: false},
+	}
+	for _, tc := range tcs {
+		assert.Equal(t, tc.allowed, tc.permission.allows(principal))
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	s := New()
+	s.GET("/public", func(c *Context) { c.String(http.StatusOK, "public") })
+	api := s.Group("/api").PreMiddlewares(APIKey(func(key string) (*Principal, bool) {
+		switch key {
+		case "admin":
+			return &Principal{ID: "admin", Roles: []string{"admin"}, Scopes: []string{"read", "write"}}, true
+		case "reader":
+			return &Principal{ID: "reader", Scopes: []string{"read"}}, true
+		}
+		return nil, false
+	})).RequireAuth()
+	api.GET("/items", func(c *Context) { c.String(http.StatusOK, "items") }).RequireScopes("read")
+	api.DELETE("/items", func(c *Context) { c.String(http.StatusOK, "deleted") }).RequireScopes("write")
+	api.Group("/admin").RequireRoles("admin").GET("/stats", func(c *Context) { c.String(http.StatusOK, "stats") })
+	s.Group("/open").PreMiddlewares(func(c *Context) {}).GET("/me", func(c *Context) {}).RequireAuth()
+	tcs := []struct {
+		method string
+		path   string
+		key    string
+		code   int
+	}{
+		{method: http.MethodGet, path: "/public", code: http.StatusOK},
+		{method: http.MethodGet, path: "/api/items", key: "reader", code: http.StatusOK},
+		{method: http.MethodDelete, path: "/api/items", key: "reader", code: http.StatusForbidden},
+		{method: http.MethodDelete, path: "/api/items", key: "admin", code: http.StatusOK},
+		{method: http.MethodGet, path: "/api/admin/stats", key: "reader", code: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/admin/stats", key: "admin", code: http.StatusOK},
+		{method: http.MethodGet, path: "/api/admin/stats", code: http.StatusUnauthorized},
+		{method: http.MethodGet, path: "/open/me", code: http.StatusUnauthorized},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.key != "" {
+			req.Header.Set(HeaderXAPIKey, tc.key)
+		}
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, tc.method+" "+tc.path)
+	}
+	unprotected := s.UnprotectedRoutes()
+	assert.Len(t, unprotected, 1)
+	assert.Equal(t, "/public", unprotected[0].Pattern)
+	assert.Len(t, s.Routes(), 5)
+}
+
+func TestGetPermissionsShared(t *testing.T) {
+	s := New()
+	o := s.Group("/o").RequireAuth().RequireScopes("read").RequireRoles("user")
+	a := o.Group("/a").RequireScopes("a").GET("/x", func(c *Context) {})
+	b := o.Group("/b").RequireScopes("b").GET("/x", func(c *Context) {})
+	// the routes share the permissions of their groups, they must not write over each other.
+	pa := a.getPermissions()
+	pb := b.getPermissions()
+	assert.Equal(t, Permission{Scopes: []string{"a"}}, pa[3])
+	assert.Equal(t, Permission{Scopes: []string{"b"}}, pb[3])
+	done := make(chan struct{})
+	for _, r := range []*Route{a, b} {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			r.getPermissions()
+		}()
+	}
+	<-done
+	<-done
+}
@@ -0,0 +1,43 @@
+package web
+
+import (
+	"net/http"
+)
+
+// MaxBodyBytes caps the request bodies of the group and its subgroups, overriding Server.MaxBodyBytes, a negative limit lifts it.
+func (rg *RouterGroup) MaxBodyBytes(limit int64) *RouterGroup {
+	rg.maxBodyBytes = limit
+	return rg
+}
+
+// getMaxBodyBytes returns the limit of the nearest group setting one, then the one of the server.
+func (rg *RouterGroup) getMaxBodyBytes(serverLimit int64) int64 {
+	for g := rg; g != nil; g = g.parent {
+		if g.maxBodyBytes != 0 {
+			return g.maxBodyBytes
+		}
+	}
+	return serverLimit
+}
+
+// BodyLimit caps the request body of the routes it is used on, the reads fail with *http.MaxBytesError beyond the limit,
+// which DefaultErrorHandler answers with 413. The limits only tighten, a body already capped keeps its smaller limit.
+func BodyLimit(limit int64) func(*Context) {
+	return func(c *Context) {
+		if limit > 0 && c.Req.Body != nil && c.Req.Body != http.NoBody {
+			c.Req.Body = http.MaxBytesReader(c.Writer, c.Req.Body, limit)
+		}
+	}
+}
+
+// limitBody heads every handler chain with the limit of the nearest group setting one, then the one of the server.
+func limitBody(c *Context) {
+	var limit int64
+	if c.server != nil {
+		limit = c.server.MaxBodyBytes
+	}
+	if c.route != nil && c.route.group != nil {
+		limit = c.route.group.getMaxBodyBytes(limit)
+	}
+	BodyLimit(limit)(c)
+}
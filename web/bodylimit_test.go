@@ -0,0 +1,102 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodyBytes(t *testing.T) {
+	s := New()
+	s.MaxBodyBytes = 10
+	echo := HandleError(func(c *Context) error {
+		b, err := io.ReadAll(c.Req.Body)
+		if err != nil {
+			return err
+		}
+		c.String(http.StatusOK, "%s", b)
+		return nil
+	})
+	s.POST("/echo", echo)
+	s.Group("/small").MaxBodyBytes(5).POST("/echo", echo)
+	uploads := s.Group("/uploads").MaxBodyBytes(-1)
+	uploads.POST("/echo", echo)
+	uploads.Group("/nested").POST("/echo", echo)
+	tcs := []struct {
+		path    string
+		body    string
+		chunked bool
+		code    int
+	}{
+		{path: "/echo", body: "0123456789", code: http.StatusOK},
+		{path: "/echo", body: "0123456789a", code: http.StatusRequestEntityTooLarge},
+		{path: "/echo", body: "0123456789a", chunked: true, code: http.StatusRequestEntityTooLarge},
+		{path: "/small/echo", body: "012345", code: http.StatusRequestEntityTooLarge},
+		{path: "/small/echo", body: "012345", chunked: true, code: http.StatusRequestEntityTooLarge},
+		{path: "/uploads/echo", body: strings.Repeat("a", 100), code: http.StatusOK},
+		{path: "/uploads/nested/echo", body: strings.Repeat("a", 100), chunked: true, code: http.StatusOK},
+	}
+	for _, tc := range tcs {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, tc.path)
+		if tc.code == http.StatusOK {
+			assert.Equal(t, tc.body, w.Body.String())
+		}
+	}
+}
+
+func TestServerTimeoutDefaults(t *testing.T) {
+	s := New()
+	assert.Equal(t, DefaultReadHeaderTimeout, s.ReadHeaderTimeout)
+	assert.Zero(t, s.ReadTimeout)
+	assert.Zero(t, s.MaxBodyBytes)
+}
+
+func TestBodyLimit(t *testing.T) {
+	var seen []error
+	s := New()
+	s.MaxBodyBytes = 10
+	// the 413 comes from reading the body in the handler, so that it goes back through the middlewares.
+	s.PreMiddlewares(func(c *Context) {
+		c.Next()
+		seen = append(seen, c.err)
+	})
+	echo := HandleError(func(c *Context) error {
+		b, err := io.ReadAll(c.Req.Body)
+		if err != nil {
+			return err
+		}
+		c.String(http.StatusOK, "%s", b)
+		return nil
+	})
+	s.POST("/echo", echo)
+	s.POST("/tight", echo).Use(BodyLimit(3))
+	s.POST("/loose", echo).Use(BodyLimit(100))
+	tcs := []struct {
+		path string
+		body string
+		code int
+	}{
+		{path: "/echo", body: "0123456789a", code: http.StatusRequestEntityTooLarge},
+		{path: "/tight", body: "0123", code: http.StatusRequestEntityTooLarge},
+		{path: "/tight", body: "012", code: http.StatusOK},
+		{path: "/loose", body: "0123456789a", code: http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range tcs {
+		seen = nil
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+		assert.Equal(t, tc.code, w.Code, tc.path)
+		if assert.Len(t, seen, 1, tc.path) && tc.code != http.StatusOK {
+			assert.Error(t, seen[0], tc.path)
+		}
+	}
+}
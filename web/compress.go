@@ -0,0 +1,283 @@
+package web
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	EncodingGzip          = "gzip"
+	EncodingDeflate       = "deflate"
+	CompressMinLength     = 1024
+	HeaderVary            = "Vary"
+	HeaderAcceptEncoding  = "Accept-Encoding"
+	HeaderContentEncoding = "Content-Encoding"
+	HeaderContentLength   = "Content-Length"
+	HeaderContentType     = "Content-Type"
+	HeaderContentRange    = "Content-Range"
+)
+
+var CompressContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/x-ndjson",
+	"image/svg+xml",
+}
+
+type (
+	CompressConfig struct {
+		// Level of gzip and deflate, from flate.HuffmanOnly to flate.BestCompression, flate.DefaultCompression if nil.
+		// It is a pointer so that flate.NoCompression, which is zero, can be selected.
+		Level *int
+		// MinLength is the minimum body size in bytes to be compressed, CompressMinLength by default.
+		MinLength int
+		// ContentTypes allowed to be compressed, "type/*" matches any subtype, CompressContentTypes by default.
+		ContentTypes []string
+	}
+
+	acceptValue struct {
+		value string
+		q     float64
+	}
+
+	compressor interface {
+		io.WriteCloser
+		Flush() error
+		Reset(io.Writer)
+	}
+
+	// compressWriter buffers the beginning of the body until it knows if the response is worth compressing.
+	compressWriter struct {
+		http.ResponseWriter
+		cfg      *CompressConfig
+		encoding string
+		pool     *sync.Pool
+		cw       compressor
+		buf      bytes.Buffer
+		status   int
+		decided  bool
+	}
+)
+
+func Compress() func(*Context) {
+	return CompressWithConfig(CompressConfig{})
+}
+
+func CompressWithConfig(cfg CompressConfig) func(*Context) {
+	level := flate.DefaultCompression
+	if cfg.Level != nil {
+		level = *cfg.Level
+	}
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		panic("Compress level should be between flate.HuffmanOnly and flate.BestCompression!")
+	}
+	if cfg.MinLength == 0 {
+		cfg.MinLength = CompressMinLength
+	}
+	if cfg.ContentTypes == nil {
+		cfg.ContentTypes = CompressContentTypes
+	}
+	pools := map[string]*sync.Pool{
+		EncodingGzip: {New: func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+		EncodingDeflate: {New: func() any {
+			w, _ := flate.NewWriter(io.Discard, level)
+			return w
+		}},
+	}
+	return func(c *Context) {
+		c.Writer.Header().Add(HeaderVary, HeaderAcceptEncoding)
+		if c.Method == http.MethodHead {
+			return
+		}
+		encoding := negotiateEncoding(c.Req.Header.Get(HeaderAcceptEncoding))
+		if encoding == "" {
+			return
+		}
+		w := &compressWriter{ResponseWriter: c.Writer, cfg: &cfg, encoding: encoding, pool: pools[encoding]}
+		c.Writer = w
+		c.Defer(func() {
+			_ = w.Close()
+		})
+	}
+}
+
+// Parse header values with quality, like Accept or Accept-Encoding, sorted by descending quality.
+func parseAcceptHeader(header string) (values []acceptValue) {
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		av := acceptValue{value: strings.ToLower(value), q: 1}
+		for _, param := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(k) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					av.q = q
+				}
+			}
+		}
+		values = append(values, av)
+	}
+	slices.SortStableFunc(values, func(a, b acceptValue) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+	return
+}
+
+func negotiateEncoding(header string) string {
+	for _, av := range parseAcceptHeader(header) {
+		if av.q <= 0 {
+			continue
+		}
+		switch av.value {
+		case EncodingGzip, EncodingDeflate:
+			return av.value
+		case "*":
+			return EncodingGzip
+		}
+	}
+	return ""
+}
+
+func matchContentType(contentType string, allowed []string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, a := range allowed {
+		if prefix, ok := strings.CutSuffix(a, "*"); ok {
+			if strings.HasPrefix(mediaType, prefix) {
+				return true
+			}
+		} else if mediaType == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.cw != nil {
+			return w.cw.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf.Write(b)
+	if w.buf.Len() >= w.cfg.MinLength {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// decide commits the header, with compression if the response is large enough and of an allowed type, then writes the buffer.
+func (w *compressWriter) decide(large bool) (err error) {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	h := w.Header()
+	if h.Get(HeaderContentType) == "" && w.buf.Len() > 0 {
+		h.Set(HeaderContentType, http.DetectContentType(w.buf.Bytes()))
+	}
+	if large && w.compressible() {
+		h.Del(HeaderContentLength)
+		h.Set(HeaderContentEncoding, w.encoding)
+		w.cw = w.pool.Get().(compressor)
+		w.cw.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		if w.cw != nil {
+			_, err = w.cw.Write(w.buf.Bytes())
+		} else {
+			_, err = w.ResponseWriter.Write(w.buf.Bytes())
+		}
+		w.buf.Reset()
+	}
+	return
+}
+
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified ||
+		w.status == http.StatusPartialContent || h.Get(HeaderContentEncoding) != "" || h.Get(HeaderContentRange) != "" {
+		return false
+	}
+	return matchContentType(h.Get(HeaderContentType), w.cfg.ContentTypes)
+}
+
+// Flush commits the response even below the minimum length, streamed responses are compressed as soon as they flush.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(true)
+	}
+	if w.cw != nil {
+		_ = w.cw.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Close() (err error) {
+	if !w.decided {
+		if w.status == 0 && w.buf.Len() == 0 {
+			return
+		}
+		if err = w.decide(false); err != nil {
+			return
+		}
+	}
+	if w.cw != nil {
+		err = w.cw.Close()
+		w.cw.Reset(io.Discard)
+		w.pool.Put(w.cw)
+		w.cw = nil
+	}
+	return
+}
+
+// discard drops the body and the status held back, so that an error response replaces them.
+func (w *compressWriter) discard() {
+	if !w.decided {
+		w.buf.Reset()
+		w.status = 0
+		w.Header().Del(HeaderContentLength)
+	}
+}
+
+func (w *compressWriter) heldStatus() int {
+	if w.decided {
+		return 0
+	}
+	return w.status
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
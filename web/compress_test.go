@@ -0,0 +1,148 @@
+package web
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tcs := []struct {
+		header   string
+		encoding string
+	}{
+		{header: "", encoding: ""},
+		{header: "gzip", encoding: EncodingGzip},
+		{header: "deflate, gzip;q=0.5", encoding: EncodingDeflate},
+		{header: "gzip;q=0, deflate;q=0.1", encoding: EncodingDeflate},
+		{header: "br, *;q=0.2", encoding: EncodingGzip},
+		{header: "identity", encoding: ""},
+	}
+	for _, tc := range tcs {
+		assert.Equal(t, tc.encoding, negotiateEncoding(tc.header), tc.header)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("sampan ", 500)
+	s := New()
+	s.PreMiddlewares(CompressWithConfig(CompressConfig{MinLength: 100}))
+	s.GET("/large", func(c *Context) { c.String(http.StatusOK, large) })
+	s.GET("/small", func(c *Context) { c.String(http.StatusOK, "small") })
+	s.GET("/binary", func(c *Context) {
+		c.SetHeader(HeaderContentType, "image/png")
+		c.Data(http.StatusOK, []byte(large))
+	})
+	s.HEAD("/large", func(c *Context) { c.String(http.StatusOK, large) })
+	tcs := []struct {
+		method   string
+		path     string
+		accept   string
+		encoding string
+	}{
+		{method: http.MethodGet, path: "/large", accept: "gzip", encoding: EncodingGzip},
+		{method: http.MethodGet, path: "/large", accept: "deflate", encoding: EncodingDeflate},
+		{method: http.MethodGet, path: "/large", accept: "", encoding: ""},
+		{method: http.MethodGet, path: "/small", accept: "gzip", encoding: ""},
+		{method: http.MethodGet, path: "/binary", accept: "gzip", encoding: ""},
+		{method: http.MethodHead, path: "/large", accept: "gzip", encoding: ""},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set(HeaderAcceptEncoding, tc.accept)
+		s.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, HeaderAcceptEncoding, w.Header().Get(HeaderVary))
+		assert.Equal(t, tc.encoding, w.Header().Get(HeaderContentEncoding), tc.path)
+		var body io.Reader = w.Body
+		switch tc.encoding {
+		case EncodingGzip:
+			body, _ = gzip.NewReader(w.Body)
+		case EncodingDeflate:
+			body = flate.NewReader(w.Body)
+		}
+		b, err := io.ReadAll(body)
+		assert.NoError(t, err)
+		if tc.path == "/small" {
+			assert.Equal(t, "small", string(b))
+		} else if tc.method != http.MethodHead {
+			assert.Equal(t, large, string(b))
+		}
+	}
+}
+
+func TestCompressError(t *testing.T) {
+	s := New()
+	s.PreMiddlewares(CompressWithConfig(CompressConfig{MinLength: 100}))
+	s.GET("/error", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		c.Error(NewHTTPError(http.StatusBadRequest))
+	})
+	s.GET("/panic", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	s.GET("/committed", func(c *Context) {
+		c.String(http.StatusOK, strings.Repeat("sampan ", 20))
+		c.Writer.(http.Flusher).Flush()
+		c.Error(NewHTTPError(http.StatusBadRequest))
+	})
+	tcs := []struct {
+		path string
+		code int
+		body string
+	}{
+		{path: "/error", code: http.StatusBadRequest, body: "Bad Request\n"},
+		{path: "/panic", code: http.StatusInternalServerError, body: "Internal Server Error\n"},
+		{path: "/committed", code: http.StatusOK, body: strings.Repeat("sampan ", 20)},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set(HeaderAcceptEncoding, EncodingGzip)
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, tc.path)
+		body, err := io.ReadAll(w.Body)
+		if w.Header().Get(HeaderContentEncoding) == EncodingGzip {
+			r, _ := gzip.NewReader(bytes.NewReader(body))
+			body, err = io.ReadAll(r)
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tc.body, string(body), tc.path)
+	}
+}
+
+func TestCompressLevel(t *testing.T) {
+	large := strings.Repeat("sampan ", 500)
+	level := flate.NoCompression
+	s := New()
+	s.PreMiddlewares(CompressWithConfig(CompressConfig{Level: &level}))
+	s.GET("/large", func(c *Context) { c.String(http.StatusOK, large) })
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set(HeaderAcceptEncoding, EncodingGzip)
+	s.ServeHTTP(w, req)
+	assert.Equal(t, EncodingGzip, w.Header().Get(HeaderContentEncoding))
+	assert.Greater(t, w.Body.Len(), len(large))
+	r, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	b, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, large, string(b))
+}
+
+func TestCompressLevelPanics(t *testing.T) {
+	for _, level := range []int{flate.HuffmanOnly - 1, flate.BestCompression + 1, 42} {
+		assert.Panics(t, func() { CompressWithConfig(CompressConfig{Level: &level}) }, level)
+	}
+	for _, level := range []int{flate.HuffmanOnly, flate.BestCompression} {
+		assert.NotPanics(t, func() { CompressWithConfig(CompressConfig{Level: &level}) }, level)
+	}
+}
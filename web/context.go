@@ -1,12 +1,20 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/ywang2728/sampan/log"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 )
 
+var _ context.Context = (*Context)(nil)
+
+// Context carries the request along the handler chain. It is pooled and reused by the next requests once the handlers
+// have returned, so it must not be kept after that, nor used by the goroutines outliving them: give them a Copy.
 type Context struct {
 	Writer     http.ResponseWriter
 	Req        *http.Request
@@ -14,17 +22,74 @@ type Context struct {
 	Method     string
 	params     map[string]string
 	StatusCode int
+	resp       *responseWriter
+	err        error
+	aborted    bool
+	route      *Route
+	handlers   []func(*Context)
+	index      int
+	requestID  string
+	logger     log.Logger
+	finalizers []func()
+	principal  *Principal
+	csrfToken  string
+	cspNonce   string
+	server     *Server
+	upload     *UploadConfig
+	store      *valueStore
+	storeMu    sync.RWMutex
+	response   responseWriter
+}
+
+// contextFork runs the rest of the chain on a copy of the Context, for the handlers which run on another goroutine
+// and may outlive the request, while the Context is reused by the next ones.
+type contextFork struct {
+	parent   *Context
+	c        *Context
+	mutex    sync.Mutex
+	done     bool
+	detached bool
+}
+
+// forkedValues keeps the cancellation of the request context of the Context, but reads the values of the one of its
+// copy, which derives from it, so that the values added by the handlers of the copy are not lost once it is joined.
+type forkedValues struct {
+	context.Context
+	values context.Context
+}
+
+func (ctx *forkedValues) Value(key any) any {
+	return ctx.values.Value(key)
 }
 
-func newContext(w http.ResponseWriter, r *http.Request) *Context {
-	return &Context{
-		Writer: w,
-		Req:    r,
-		Path:   r.URL.Path,
-		Method: strings.ToUpper(r.Method),
+func newContext(w http.ResponseWriter, r *http.Request) (c *Context) {
+	c = &Context{}
+	c.reset(w, r)
+	return
+}
+
+// reset prepares a pooled Context for the request, keeping the capacity of the finalizers.
+func (c *Context) reset(w http.ResponseWriter, r *http.Request) {
+	clear(c.finalizers)
+	finalizers := c.finalizers[:0]
+	*c = Context{
+		Req:        r,
+		Path:       r.URL.Path,
+		Method:     strings.ToUpper(r.Method),
+		finalizers: finalizers,
+	}
+	if w != nil {
+		c.setWriter(w)
 	}
 }
 
+// setWriter makes w the response of the Context, recording its status and if it has been committed.
+func (c *Context) setWriter(w http.ResponseWriter) {
+	c.response = responseWriter{ResponseWriter: w, status: http.StatusOK}
+	c.resp = &c.response
+	c.Writer = c.resp
+}
+
 func (c *Context) PostForm(key string) string {
 	return c.Req.FormValue(key)
 }
@@ -77,6 +142,157 @@ func (c *Context) HTML(code int, value string) {
 	}
 }
 
+// Deadline, Done, Err and Value delegate to the context of the request, so that Context can be passed as context.Context.
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.Req.Context().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	return c.Req.Context().Err()
+}
+
+func (c *Context) Value(key any) any {
+	return c.Req.Context().Value(key)
+}
+
+// Next runs the remaining handlers of the chain inside the current one, so that it can act once they have returned.
+// The handlers not calling it are followed by the next ones anyway.
+func (c *Context) Next() {
+	for c.index++; c.index < len(c.handlers) && !c.aborted; c.index++ {
+		c.handlers[c.index](c)
+	}
+}
+
+// Abort stops the remaining handlers of the chain.
+func (c *Context) Abort() {
+	c.aborted = true
+}
+
+func (c *Context) IsAborted() bool {
+	return c.aborted
+}
+
+// Error records the error for Server.ErrorHandler and aborts the chain.
+func (c *Context) Error(err error) {
+	c.err = err
+	c.Abort()
+}
+
+// Written reports if the response header has been committed.
+func (c *Context) Written() bool {
+	return c.resp != nil && c.resp.written
+}
+
+// Defer registers a function to run once the response is completed, the last registered runs first.
+func (c *Context) Defer(f func()) {
+	c.finalizers = append(c.finalizers, f)
+}
+
+// finish runs the finalizers, a panicking one does not prevent the others from running. As the response is completed,
+// its panic is only reported to Server.PanicHandler, or logged without one.
+func (c *Context) finish() {
+	for i := len(c.finalizers) - 1; i >= 0; i-- {
+		c.runFinalizer(c.finalizers[i])
+	}
+}
+
+func (c *Context) runFinalizer(f func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			if c.server != nil && c.server.PanicHandler != nil {
+				c.server.PanicHandler(c, err)
+			} else {
+				c.Logger().Errorf("Finalizer panic: %v", err)
+			}
+		}
+	}()
+	f()
+}
+
+// Route returns the matched route, nil if none matched.
+func (c *Context) Route() *Route {
+	return c.route
+}
+
+// Pattern returns the registered path of the matched route.
+func (c *Context) Pattern() string {
+	if c.route == nil {
+		return ""
+	}
+	return c.route.Pattern
+}
+
+// Logger returns a logger attaching the request ID, the route and the method to every entry.
+func (c *Context) Logger() log.Logger {
+	if c.logger == nil {
+		c.logger = log.WithMeta(map[string]any{"request_id": c.requestID, "route": c.Pattern(), "method": c.Method})
+	}
+	return c.logger
+}
+
+// Copy returns a copy of the Context for a goroutine outliving the handler, which keeps the request, its parameters and
+// values, the route, the request ID and the principal. It has no handlers left to run and its writer discards the
+// response, which belongs to the Context.
+func (c *Context) Copy() *Context {
+	cp := c.fork().c
+	cp.setWriter(discardWriter{header: http.Header{}})
+	cp.handlers, cp.index, cp.aborted = nil, 0, true
+	return cp
+}
+
+// fork copies the Context, sharing its store, the copy gets its own finalizers and should get its own writer by setWriter.
+func (c *Context) fork() *contextFork {
+	c.initStore()
+	return &contextFork{parent: c, c: &Context{
+		Writer: c.Writer, Req: c.Req, Path: c.Path, Method: c.Method, params: c.params, StatusCode: c.StatusCode,
+		err: c.err, aborted: c.aborted, route: c.route, handlers: c.handlers, index: c.index, requestID: c.requestID,
+		logger: c.logger, principal: c.principal, csrfToken: c.csrfToken, cspNonce: c.cspNonce, server: c.server,
+		upload: c.upload, store: c.store,
+	}}
+}
+
+// run calls the handler with the copy, whose finalizers run as soon as it returns, as the response they complete is
+// committed after. The panics of a detached copy can only be reported to the panic handler.
+func (f *contextFork) run(handler func(*Context)) {
+	defer func() {
+		f.c.finish()
+		f.mutex.Lock()
+		f.done = true
+		detached := f.detached
+		f.mutex.Unlock()
+		if detached {
+			if err := recover(); err != nil && f.c.server != nil && f.c.server.PanicHandler != nil {
+				f.c.server.PanicHandler(f.c, err)
+			}
+		}
+	}()
+	handler(f.c)
+}
+
+// join takes back the state of the copy once its handlers have returned, except its writer and request, of which only the
+// context values are taken back. It reports false if they are still running, the copy is then detached and finishes on
+// its own.
+func (f *contextFork) join() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if !f.done {
+		f.detached = true
+		return false
+	}
+	c, fc := f.parent, f.c
+	c.params, c.StatusCode, c.err, c.aborted, c.index = fc.params, fc.StatusCode, fc.err, fc.aborted, fc.index
+	c.logger, c.principal, c.csrfToken, c.cspNonce, c.upload = fc.logger, fc.principal, fc.csrfToken, fc.cspNonce, fc.upload
+	c.requestID = fc.requestID
+	if ctx := fc.Req.Context(); ctx != c.Req.Context() {
+		c.Req = c.Req.WithContext(&forkedValues{Context: c.Req.Context(), values: ctx})
+	}
+	return true
+}
+
 func (c *Context) setParams(params map[string]string) {
 	c.params = params
 }
@@ -1 +1,37 @@
 package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextCopy(t *testing.T) {
+	copies := make(chan *Context, 1)
+	s := New()
+	s.PreMiddlewares(RequestID())
+	s.GET(`/users/{(?P<id>\d+)}`, func(c *Context) {
+		c.Set("user", c.params["id"])
+		copies <- c.Copy()
+		c.String(http.StatusOK, "ok")
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(HeaderXRequestID, "first")
+	s.ServeHTTP(w, req)
+	cp := <-copies
+	// the next request reuses the Context, the copy keeps the state of the first one.
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2", nil))
+	<-copies
+	assert.Equal(t, "/users/1", cp.Path)
+	assert.Equal(t, "1", cp.params["id"])
+	assert.Equal(t, "first", cp.RequestID())
+	user, _ := cp.Get("user")
+	assert.Equal(t, "1", user)
+	assert.Equal(t, `/users/{(?P<id>\d+)}`, cp.Pattern())
+	cp.String(http.StatusTeapot, "late")
+	cp.Next()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+}
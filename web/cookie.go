@@ -0,0 +1,219 @@
+package web
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	KeyringMinKeyLength = 32
+	// CookieMaxLength is the size that browsers are guaranteed to store, name and attributes included.
+	CookieMaxLength = 4096
+)
+
+var (
+	ErrCookieInvalid  = errors.New("invalid cookie")
+	ErrCookieTooLarge = errors.New("cookie is too large")
+	ErrNoKeyring      = errors.New("server keyring is not set")
+)
+
+type (
+	// Keyring signs and encrypts with its first key, and verifies and decrypts with any of them.
+	// Rotating a key is adding the new one first, then removing the old one once its cookies have expired.
+	Keyring struct {
+		keys []keyringKey
+	}
+
+	keyringKey struct {
+		sign []byte
+		aead cipher.AEAD
+	}
+)
+
+func NewKeyring(keys ...[]byte) *Keyring {
+	if len(keys) == 0 {
+		panic("Keyring keys should not be empty!")
+	}
+	kr := &Keyring{keys: make([]keyringKey, len(keys))}
+	for i, key := range keys {
+		if len(key) < KeyringMinKeyLength {
+			panic("Keyring keys should be at least 32 bytes long!")
+		}
+		block, err := aes.NewCipher(deriveKey(key, "encrypt"))
+		if err != nil {
+			panic(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(err)
+		}
+		kr.keys[i] = keyringKey{sign: deriveKey(key, "sign"), aead: aead}
+	}
+	return kr
+}
+
+// deriveKey separates the signing and the encryption keys, so that the same secret is never used for both.
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("sampan keyring " + purpose))
+	return mac.Sum(nil)
+}
+
+func (k *keyringKey) mac(name string, value string) []byte {
+	mac := hmac.New(sha256.New, k.sign)
+	mac.Write([]byte(name + "=" + value))
+	return mac.Sum(nil)
+}
+
+// Sign binds the value to the name, so that a signed value can not be replayed under another name.
+func (kr *Keyring) Sign(name string, value string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(value)) + "." + enc.EncodeToString(kr.keys[0].mac(name, value))
+}
+
+func (kr *Keyring) Verify(name string, signed string) (value string, ok bool) {
+	encoded, sig, found := strings.Cut(signed, ".")
+	if !found {
+		return
+	}
+	b, errV := base64.RawURLEncoding.DecodeString(encoded)
+	mac, errS := base64.RawURLEncoding.DecodeString(sig)
+	if errV != nil || errS != nil {
+		return
+	}
+	for i := range kr.keys {
+		if hmac.Equal(kr.keys[i].mac(name, string(b)), mac) {
+			return string(b), true
+		}
+	}
+	return
+}
+
+// Encrypt seals the value with AES-GCM, the name being authenticated as additional data.
+func (kr *Keyring) Encrypt(name string, value string) (string, error) {
+	aead := kr.keys[0].aead
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(value), []byte(name))), nil
+}
+
+func (kr *Keyring) Decrypt(name string, encrypted string) (value string, ok bool) {
+	b, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return
+	}
+	for i := range kr.keys {
+		aead := kr.keys[i].aead
+		if len(b) < aead.NonceSize() {
+			return
+		}
+		if plain, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(name)); err == nil {
+			return string(plain), true
+		}
+	}
+	return
+}
+
+// Cookie returns the value of the named cookie, http.ErrNoCookie if it is missing.
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
+
+// SetCookie defaults the path to "/" and SameSite to Lax, and sets Secure over HTTPS or when SameSite is None.
+func (c *Context) SetCookie(cookie *http.Cookie) error {
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	if c.Scheme() == "https" || cookie.SameSite == http.SameSiteNoneMode {
+		cookie.Secure = true
+	}
+	v := cookie.String()
+	if v == "" {
+		return ErrCookieInvalid
+	}
+	if len(v) > CookieMaxLength {
+		return ErrCookieTooLarge
+	}
+	c.Writer.Header().Add("Set-Cookie", v)
+	return nil
+}
+
+func (c *Context) keyring() (*Keyring, error) {
+	if c.server == nil || c.server.Keyring == nil {
+		return nil, ErrNoKeyring
+	}
+	return c.server.Keyring, nil
+}
+
+// SetSignedCookie signs the value with Server.Keyring, the client can read it but not alter it.
+func (c *Context) SetSignedCookie(cookie *http.Cookie) error {
+	kr, err := c.keyring()
+	if err != nil {
+		return err
+	}
+	signed := *cookie
+	signed.Value = kr.Sign(cookie.Name, cookie.Value)
+	return c.SetCookie(&signed)
+}
+
+// SignedCookie returns the verified value of the named cookie, ErrCookieInvalid if it has been altered.
+func (c *Context) SignedCookie(name string) (string, error) {
+	kr, err := c.keyring()
+	if err != nil {
+		return "", err
+	}
+	signed, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	value, ok := kr.Verify(name, signed)
+	if !ok {
+		return "", ErrCookieInvalid
+	}
+	return value, nil
+}
+
+// SetEncryptedCookie encrypts the value with Server.Keyring, the client can neither read nor alter it.
+func (c *Context) SetEncryptedCookie(cookie *http.Cookie) error {
+	kr, err := c.keyring()
+	if err != nil {
+		return err
+	}
+	encrypted := *cookie
+	if encrypted.Value, err = kr.Encrypt(cookie.Name, cookie.Value); err != nil {
+		return err
+	}
+	return c.SetCookie(&encrypted)
+}
+
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	kr, err := c.keyring()
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	value, ok := kr.Decrypt(name, encrypted)
+	if !ok {
+		return "", ErrCookieInvalid
+	}
+	return value, nil
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"bytes"
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var (
+	oldCookieKey = bytes.Repeat([]byte("o"), 32)
+	newCookieKey = bytes.Repeat([]byte("n"), 32)
+)
+
+func TestKeyring(t *testing.T) {
+	assert.Panics(t, func() { NewKeyring() })
+	assert.Panics(t, func() { NewKeyring([]byte("short")) })
+
+	old := NewKeyring(oldCookieKey)
+	rotated := NewKeyring(newCookieKey, oldCookieKey)
+	signed := old.Sign("cart", "42")
+	value, ok := rotated.Verify("cart", signed)
+	assert.True(t, ok)
+	assert.Equal(t, "42", value)
+	_, ok = rotated.Verify("user", signed)
+	assert.False(t, ok)
+	_, ok = NewKeyring(newCookieKey).Verify("cart", signed)
+	assert.False(t, ok)
+	_, ok = rotated.Verify("cart", "NDM."+strings.SplitN(signed, ".", 2)[1])
+	assert.False(t, ok)
+
+	encrypted, err := old.Encrypt("cart", "secret")
+	assert.NoError(t, err)
+	assert.NotContains(t, encrypted, "secret")
+	value, ok = rotated.Decrypt("cart", encrypted)
+	assert.True(t, ok)
+	assert.Equal(t, "secret", value)
+	_, ok = rotated.Decrypt("user", encrypted)
+	assert.False(t, ok)
+	_, ok = NewKeyring(newCookieKey).Decrypt("cart", encrypted)
+	assert.False(t, ok)
+	_, ok = rotated.Decrypt("cart", "AAAA")
+	assert.False(t, ok)
+}
+
+func TestSetCookie(t *testing.T) {
+	tcs := []struct {
+		cookie   *http.Cookie
+		tls      bool
+		expected string
+	}{
+		{cookie: &http.Cookie{Name: "theme", Value: "dark"}, expected: "theme=dark; Path=/; SameSite=Lax"},
+		{cookie: &http.Cookie{Name: "theme", Value: "dark"}, tls: true, expected: "theme=dark; Path=/; Secure; SameSite=Lax"},
+		{cookie: &http.Cookie{Name: "id", Value: "1", Path: "/api", HttpOnly: true, SameSite: http.SameSiteNoneMode}, expected: "id=1; Path=/api; HttpOnly; Secure; SameSite=None"},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		c := newContext(w, req)
+		assert.NoError(t, c.SetCookie(tc.cookie))
+		assert.Equal(t, tc.expected, w.Header().Get("Set-Cookie"))
+	}
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.ErrorIs(t, c.SetCookie(&http.Cookie{Name: "big", Value: strings.Repeat("a", CookieMaxLength)}), ErrCookieTooLarge)
+	assert.ErrorIs(t, c.SetCookie(&http.Cookie{Name: "bad name", Value: "a"}), ErrCookieInvalid)
+	assert.ErrorIs(t, c.SetSignedCookie(&http.Cookie{Name: "a", Value: "a"}), ErrNoKeyring)
+}
+
+func TestSignedAndEncryptedCookies(t *testing.T) {
+	s := New()
+	s.Keyring = NewKeyring(oldCookieKey)
+	s.GET("/set", func(c *Context) {
+		assert.NoError(t, c.SetSignedCookie(&http.Cookie{Name: "user", Value: "alice"}))
+		assert.NoError(t, c.SetEncryptedCookie(&http.Cookie{Name: "cart", Value: "book:2"}))
+	})
+	s.GET("/get", func(c *Context) {
+		user, errU := c.SignedCookie("user")
+		cart, errC := c.EncryptedCookie("cart")
+		c.String(http.StatusOK, "%s %v %s %v", user, errU, cart, errC)
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/set", nil))
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 2)
+	assert.NotContains(t, cookies[1].Value, "book")
+
+	s.Keyring = NewKeyring(newCookieKey, oldCookieKey)
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	assert.Equal(t, "alice <nil> book:2 <nil>", w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(&http.Cookie{Name: "user", Value: "YWRtaW4." + strings.SplitN(cookies[0].Value, ".", 2)[1]})
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	assert.Equal(t, " invalid cookie  http: named cookie not present", w.Body.String())
+}
@@ -0,0 +1,169 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	CSRFCookieName   = "_csrf"
+	CSRFFormField    = "_csrf"
+	CSRFTokenLength  = 32
+	HeaderXCSRFToken = "X-CSRF-Token"
+	HeaderOrigin     = "Origin"
+	HeaderReferer    = "Referer"
+)
+
+var ErrCSRFNoKeyring = errors.New("CSRF double-submit cookie requires Server.Keyring or CSRFConfig.Session")
+
+type (
+	// CSRFSessionStore binds the synchronizer tokens to the server side session of the request.
+	CSRFSessionStore interface {
+		// Token returns the token of the session, empty if it has none yet.
+		Token(c *Context) string
+		SetToken(c *Context, token string)
+	}
+
+	CSRFConfig struct {
+		// Session enables the synchronizer tokens, the double-submit cookie is used if nil.
+		// The cookie is signed with Server.Keyring, so that a cookie planted by a sibling subdomain or over HTTP is rejected,
+		// the keyring is then required.
+		Session CSRFSessionStore
+		// Cookie of the double-submit token, CSRFCookieName by default.
+		CookieName string
+		CookiePath string
+		// CookieMaxAge in seconds, the cookie lasts for the browser session if zero.
+		CookieMaxAge int
+		CookieSecure bool
+		// CookieSameSite is http.SameSiteLaxMode by default.
+		CookieSameSite http.SameSite
+		// Header and form field carrying the submitted token, X-CSRF-Token and CSRFFormField by default.
+		Header    string
+		FormField string
+		// TrustedOrigins allowed besides the origin of the request host, like "https://admin.example.com".
+		TrustedOrigins []string
+	}
+)
+
+func (c *Context) CSRFToken() string {
+	return c.csrfToken
+}
+
+// CSRFExempt opts the route out of the CSRF protection, for webhooks authenticated by other means.
+func (r *Route) CSRFExempt() *Route {
+	r.csrfExempt = true
+	return r
+}
+
+func newCSRFToken() string {
+	b := make([]byte, CSRFTokenLength)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || method == http.MethodTrace
+}
+
+// CSRF protects with the double-submit cookie, which is signed with Server.Keyring: without a keyring every request,
+// the safe ones included, fails with ErrCSRFNoKeyring. Use CSRFWithConfig with a Session to do without it.
+func CSRF() func(*Context) {
+	return CSRFWithConfig(CSRFConfig{})
+}
+
+func CSRFWithConfig(cfg CSRFConfig) func(*Context) {
+	if cfg.CookieName == "" {
+		cfg.CookieName = CSRFCookieName
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.CookieSameSite == 0 {
+		cfg.CookieSameSite = http.SameSiteLaxMode
+	}
+	if cfg.Header == "" {
+		cfg.Header = HeaderXCSRFToken
+	}
+	if cfg.FormField == "" {
+		cfg.FormField = CSRFFormField
+	}
+	return func(c *Context) {
+		if route := c.Route(); route != nil && route.csrfExempt {
+			return
+		}
+		var token string
+		if cfg.Session != nil {
+			token = cfg.Session.Token(c)
+		} else if value, err := c.SignedCookie(cfg.CookieName); err == nil {
+			token = value
+		} else if errors.Is(err, ErrNoKeyring) {
+			c.Error(ErrCSRFNoKeyring)
+			return
+		}
+		if token == "" {
+			token = newCSRFToken()
+			if cfg.Session != nil {
+				cfg.Session.SetToken(c, token)
+			} else if err := c.SetSignedCookie(&http.Cookie{
+				Name:     cfg.CookieName,
+				Value:    token,
+				Path:     cfg.CookiePath,
+				MaxAge:   cfg.CookieMaxAge,
+				Secure:   cfg.CookieSecure,
+				HttpOnly: true,
+				SameSite: cfg.CookieSameSite,
+			}); err != nil {
+				c.Error(NewHTTPError(http.StatusInternalServerError).WithError(err))
+				return
+			}
+		}
+		c.csrfToken = token
+		c.Writer.Header().Add(HeaderVary, "Cookie")
+		if isSafeMethod(c.Method) {
+			return
+		}
+		if !checkCSRFOrigin(c, cfg.TrustedOrigins) {
+			c.Error(NewHTTPError(http.StatusForbidden, "invalid CSRF origin"))
+			return
+		}
+		submitted := c.Req.Header.Get(cfg.Header)
+		if submitted == "" {
+			submitted = c.Req.PostFormValue(cfg.FormField)
+		}
+		if submitted == "" || !ConstantTimeEqual(submitted, token) {
+			c.Error(NewHTTPError(http.StatusForbidden, "invalid CSRF token"))
+		}
+	}
+}
+
+// checkCSRFOrigin compares the Origin, or the Referer if there is no Origin, with the scheme and host of the request and the
+// trusted origins.
+func checkCSRFOrigin(c *Context, trusted []string) bool {
+	origin := c.Req.Header.Get(HeaderOrigin)
+	if origin == "" {
+		referer := c.Req.Header.Get(HeaderReferer)
+		if referer == "" {
+			return true
+		}
+		u, err := url.Parse(referer)
+		if err != nil || u.Host == "" {
+			return false
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Scheme, c.Scheme()) && strings.EqualFold(u.Host, c.Host()) {
+		return true
+	}
+	return slices.ContainsFunc(trusted, func(o string) bool {
+		return strings.EqualFold(strings.TrimSuffix(o, "/"), origin)
+	})
+}
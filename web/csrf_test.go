@@ -0,0 +1,119 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type testCSRFSession struct {
+	token string
+}
+
+func (s *testCSRFSession) Token(c *Context) string {
+	return s.token
+}
+
+func (s *testCSRFSession) SetToken(c *Context, token string) {
+	s.token = token
+}
+
+func TestCSRFDoubleSubmitCookie(t *testing.T) {
+	s := New()
+	s.Keyring = NewKeyring([]byte(strings.Repeat("k", KeyringMinKeyLength)))
+	admin := s.Group("/admin").PreMiddlewares(CSRFWithConfig(CSRFConfig{TrustedOrigins: []string{"https://trusted.example.com"}}))
+	admin.GET("/form", func(c *Context) { c.String(http.StatusOK, c.CSRFToken()) })
+	admin.POST("/form", func(c *Context) { c.String(http.StatusOK, "saved") })
+	admin.POST("/hook", func(c *Context) { c.String(http.StatusOK, "hooked") }).CSRFExempt()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/form", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	token := w.Body.String()
+	value, ok := s.Keyring.Verify(CSRFCookieName, cookies[0].Value)
+	assert.True(t, ok)
+	assert.Equal(t, token, value)
+	assert.True(t, cookies[0].HttpOnly)
+	// A cookie planted by a sibling subdomain is not signed by the server.
+	forged := &http.Cookie{Name: CSRFCookieName, Value: "forged"}
+
+	tcs := []struct {
+		target string
+		cookie *http.Cookie
+		form   string
+		header string
+		origin string
+		code   int
+	}{
+		{target: "/admin/form", form: CSRFFormField + "=" + token, code: http.StatusOK},
+		{target: "/admin/form", header: token, origin: "http://example.com", code: http.StatusOK},
+		{target: "https://example.com/admin/form", header: token, origin: "https://example.com", code: http.StatusOK},
+		{target: "https://example.com/admin/form", header: token, origin: "http://example.com", code: http.StatusForbidden},
+		{target: "/admin/form", header: token, origin: "https://trusted.example.com", code: http.StatusOK},
+		{target: "/admin/form", header: token, origin: "https://evil.com", code: http.StatusForbidden},
+		{target: "/admin/form", form: CSRFFormField + "=wrong", code: http.StatusForbidden},
+		{target: "/admin/form", cookie: forged, header: "forged", code: http.StatusForbidden},
+		{target: "/admin/form", code: http.StatusForbidden},
+		{target: "/admin/hook", code: http.StatusOK},
+	}
+	for _, tc := range tcs {
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.form))
+		req.Header.Set(HeaderContentType, "application/x-www-form-urlencoded")
+		if tc.cookie != nil {
+			req.AddCookie(tc.cookie)
+		} else {
+			req.AddCookie(cookies[0])
+		}
+		if tc.header != "" {
+			req.Header.Set(HeaderXCSRFToken, tc.header)
+		}
+		if tc.origin != "" {
+			req.Header.Set(HeaderOrigin, tc.origin)
+		}
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, tc)
+	}
+}
+
+func TestCSRFNoKeyring(t *testing.T) {
+	var handled error
+	s := New()
+	s.ErrorHandler = func(c *Context, err error) {
+		handled = err
+		DefaultErrorHandler(c, err)
+	}
+	s.PreMiddlewares(CSRF())
+	s.GET("/form", func(c *Context) { c.String(http.StatusOK, c.CSRFToken()) })
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+	assert.ErrorIs(t, handled, ErrCSRFNoKeyring)
+}
+
+func TestCSRFSession(t *testing.T) {
+	session := &testCSRFSession{}
+	s := New()
+	s.PreMiddlewares(CSRFWithConfig(CSRFConfig{Session: session}))
+	s.GET("/form", func(c *Context) { c.String(http.StatusOK, c.CSRFToken()) })
+	s.PUT("/form", func(c *Context) { c.String(http.StatusOK, "saved") })
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+	assert.Empty(t, w.Result().Cookies())
+	assert.Equal(t, session.token, w.Body.String())
+
+	form := url.Values{CSRFFormField: {session.token}}
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/form", strings.NewReader(form.Encode()))
+	req.Header.Set(HeaderContentType, "application/x-www-form-urlencoded")
+	req.Header.Set(HeaderReferer, "http://example.com/form")
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
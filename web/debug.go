@@ -0,0 +1,97 @@
+package web
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"net/netip"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"time"
+)
+
+type runtimeStats struct {
+	GoVersion    string    `json:"go_version"`
+	GOMAXPROCS   int       `json:"gomaxprocs"`
+	NumCPU       int       `json:"num_cpu"`
+	Goroutines   int       `json:"goroutines"`
+	CgoCalls     int64     `json:"cgo_calls"`
+	GCPercent    int       `json:"gc_percent"`
+	NumGC        uint32    `json:"num_gc"`
+	LastGC       time.Time `json:"last_gc"`
+	PauseTotalNs uint64    `json:"pause_total_ns"`
+	Alloc        uint64    `json:"alloc"`
+	TotalAlloc   uint64    `json:"total_alloc"`
+	Sys          uint64    `json:"sys"`
+	Mallocs      uint64    `json:"mallocs"`
+	Frees        uint64    `json:"frees"`
+	HeapAlloc    uint64    `json:"heap_alloc"`
+	HeapInuse    uint64    `json:"heap_inuse"`
+	HeapIdle     uint64    `json:"heap_idle"`
+	HeapObjects  uint64    `json:"heap_objects"`
+	StackInuse   uint64    `json:"stack_inuse"`
+}
+
+// LoopbackOnly is the default guard of the debug endpoints, answering 403 to the clients which are not local.
+func LoopbackOnly(c *Context) {
+	if addr, err := netip.ParseAddr(c.ClientIP()); err != nil || !addr.IsLoopback() {
+		c.Error(NewHTTPError(http.StatusForbidden))
+	}
+}
+
+// MountDebug serves pprof under prefix+"/pprof/", the expvar variables on prefix+"/vars" and the runtime statistics
+// on prefix+"/runtime", behind the guard, LoopbackOnly if nil. It returns the group of the debug endpoints, which are
+// left out of the OpenAPI document.
+func (rg *RouterGroup) MountDebug(prefix string, guard func(*Context)) *RouterGroup {
+	if guard == nil {
+		guard = LoopbackOnly
+	}
+	g := rg.Group(prefix).PreMiddlewares(guard)
+	// pprof.Index only serves the profiles under /debug/pprof/, the named profiles are served by their own handler.
+	g.GET("/pprof/", WrapHandlerFunc(pprof.Index)).Hide()
+	g.GET("/pprof/cmdline", WrapHandlerFunc(pprof.Cmdline)).Hide()
+	g.GET("/pprof/profile", WrapHandlerFunc(pprof.Profile)).Hide()
+	g.GET("/pprof/symbol", WrapHandlerFunc(pprof.Symbol)).Hide()
+	g.POST("/pprof/symbol", WrapHandlerFunc(pprof.Symbol)).Hide()
+	g.GET("/pprof/trace", WrapHandlerFunc(pprof.Trace)).Hide()
+	g.GET(`/pprof/{(?P<profile>[a-z_]+)}`, func(c *Context) {
+		pprof.Handler(c.params["profile"]).ServeHTTP(c.Writer, c.Req)
+	}).Hide()
+	g.GET("/vars", WrapHandler(expvar.Handler())).Hide()
+	g.GET("/runtime", func(c *Context) {
+		c.JSON(http.StatusOK, readRuntimeStats())
+	}).Hide()
+	return g
+}
+
+func readRuntimeStats() (stats runtimeStats) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	// GOGC is read from the runtime metrics, as debug.SetGCPercent would change it while reading it.
+	gogc := []rtmetrics.Sample{{Name: "/gc/gogc:percent"}}
+	rtmetrics.Read(gogc)
+	stats = runtimeStats{
+		GoVersion:    runtime.Version(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		NumCPU:       runtime.NumCPU(),
+		Goroutines:   runtime.NumGoroutine(),
+		CgoCalls:     runtime.NumCgoCall(),
+		GCPercent:    int(gogc[0].Value.Uint64()),
+		NumGC:        m.NumGC,
+		PauseTotalNs: m.PauseTotalNs,
+		Alloc:        m.Alloc,
+		TotalAlloc:   m.TotalAlloc,
+		Sys:          m.Sys,
+		Mallocs:      m.Mallocs,
+		Frees:        m.Frees,
+		HeapAlloc:    m.HeapAlloc,
+		HeapInuse:    m.HeapInuse,
+		HeapIdle:     m.HeapIdle,
+		HeapObjects:  m.HeapObjects,
+		StackInuse:   m.StackInuse,
+	}
+	if m.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(m.LastGC))
+	}
+	return
+}
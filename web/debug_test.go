@@ -0,0 +1,61 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMountDebug(t *testing.T) {
+	s := New()
+	s.rg.MountDebug("/debug", nil)
+	s.Group("/admin").MountDebug("/debug", func(c *Context) {
+		if c.Req.Header.Get("X-Admin-Token") != "secret" {
+			c.Error(NewHTTPError(http.StatusUnauthorized))
+		}
+	})
+	tcs := []struct {
+		path     string
+		remote   string
+		header   map[string]string
+		code     int
+		contains string
+	}{
+		{path: "/debug/pprof/", code: http.StatusForbidden},
+		{path: "/debug/runtime", remote: "10.0.0.1:1234", code: http.StatusForbidden},
+		{path: "/debug/pprof/", remote: "127.0.0.1:1234", code: http.StatusOK, contains: "goroutine"},
+		{path: "/debug/pprof/goroutine?debug=1", remote: "127.0.0.1:1234", code: http.StatusOK, contains: "goroutine profile"},
+		{path: "/debug/pprof/heap?debug=1", remote: "[::1]:1234", code: http.StatusOK, contains: "heap profile"},
+		{path: "/debug/pprof/unknown", remote: "127.0.0.1:1234", code: http.StatusNotFound},
+		{path: "/debug/pprof/cmdline", remote: "127.0.0.1:1234", code: http.StatusOK},
+		{path: "/debug/vars", remote: "127.0.0.1:1234", code: http.StatusOK, contains: `"memstats"`},
+		{path: "/admin/debug/vars", remote: "127.0.0.1:1234", code: http.StatusUnauthorized},
+		{path: "/admin/debug/vars", header: map[string]string{"X-Admin-Token": "secret"}, code: http.StatusOK, contains: `"cmdline"`},
+	}
+	for _, tc := range tcs {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.remote != "" {
+			req.RemoteAddr = tc.remote
+		}
+		for k, v := range tc.header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, tc.path)
+		assert.Contains(t, w.Body.String(), tc.contains, tc.path)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats runtimeStats
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Positive(t, stats.Goroutines)
+	assert.Positive(t, stats.HeapAlloc)
+	assert.NotEmpty(t, stats.GoVersion)
+}
@@ -0,0 +1,92 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+type HTTPError struct {
+	Code int
+	// Message is exposed to the client, the wrapped error is kept for logging only.
+	Message string
+	Err     error
+}
+
+func NewHTTPError(code int, message ...string) *HTTPError {
+	e := &HTTPError{Code: code, Message: http.StatusText(code)}
+	if len(message) > 0 {
+		e.Message = message[0]
+	}
+	return e
+}
+
+func (e *HTTPError) WithError(err error) *HTTPError {
+	e.Err = err
+	return e
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("code=%d, message=%s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("code=%d, message=%s, error=%v", e.Code, e.Message, e.Err)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// HandleError adapts a handler returning error to the handler chain, the returned error is passed to Server.ErrorHandler.
+func HandleError(handler func(*Context) error) func(*Context) {
+	return func(c *Context) {
+		if err := handler(c); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+// DefaultErrorHandler renders the error as JSON, HTML or plain text according to the Accept header,
+// errors other than HTTPError are hidden behind a 500 response.
+func DefaultErrorHandler(c *Context, err error) {
+	var he *HTTPError
+	var mbe *http.MaxBytesError
+	switch {
+	case errors.As(err, &he):
+	case errors.As(err, &mbe):
+		he = NewHTTPError(http.StatusRequestEntityTooLarge).WithError(err)
+	default:
+		log.Printf("Handle error %4s - %s, #%v", c.Method, c.Path, err)
+		he = NewHTTPError(http.StatusInternalServerError)
+	}
+	if c.Written() {
+		return
+	}
+	accept := c.Req.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "application/json"):
+		c.JSON(he.Code, map[string]any{"code": he.Code, "message": he.Message})
+	case strings.Contains(accept, "text/html"):
+		c.HTML(he.Code, fmt.Sprintf("<!DOCTYPE html><html><head><title>%d %s</title></head><body><h1>%d %s</h1></body></html>",
+			he.Code, http.StatusText(he.Code), he.Code, html.EscapeString(he.Message)))
+	default:
+		c.String(he.Code, "%s\n", he.Message)
+	}
+}
+
+// DefaultPanicHandler logs the recovered value with the traceback of the panicking goroutine.
+func DefaultPanicHandler(c *Context, recovered any) {
+	msg := strings.Builder{}
+	msg.WriteString(fmt.Sprintf("%s\n%s", recovered, "\nTraceback:"))
+	var pcs [32]uintptr
+	for _, pc := range pcs[:runtime.Callers(4, pcs[:])] {
+		fn := runtime.FuncForPC(pc)
+		file, line := fn.FileLine(pc)
+		msg.WriteString(fmt.Sprintf("\n\t%s:%d", file, line))
+	}
+	log.Printf("%s\n\n", msg.String())
+}
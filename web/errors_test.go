@@ -0,0 +1,86 @@
+package web
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	cause := errors.New("cause")
+	e := NewHTTPError(http.StatusBadRequest).WithError(cause)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), e.Message)
+	assert.ErrorIs(t, e, cause)
+	assert.Equal(t, "invalid id", NewHTTPError(http.StatusBadRequest, "invalid id").Message)
+}
+
+func TestDefaultErrorHandler(t *testing.T) {
+	tcs := []struct {
+		accept      string
+		err         error
+		code        int
+		contentType string
+		body        string
+	}{
+		{accept: "", err: NewHTTPError(http.StatusConflict, "conflict"), code: http.StatusConflict, contentType: "text/plain", body: "conflict\n"},
+		{accept: "application/json", err: NewHTTPError(http.StatusConflict, "conflict"), code: http.StatusConflict, contentType: "application/json", body: `{"code":409,"message":"conflict"}` + "\n"},
+		{accept: "text/html", err: NewHTTPError(http.StatusConflict, "<b>"), code: http.StatusConflict, contentType: "text/html", body: "&lt;b&gt;"},
+		{accept: "", err: errors.New("secret"), code: http.StatusInternalServerError, contentType: "text/plain", body: "Internal Server Error\n"},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", tc.accept)
+		DefaultErrorHandler(newContext(w, req), tc.err)
+		assert.Equal(t, tc.code, w.Code)
+		assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), tc.body)
+	}
+}
+
+func TestServerHandleError(t *testing.T) {
+	s := New()
+	s.GET("/error", HandleError(func(c *Context) error {
+		return NewHTTPError(http.StatusForbidden, "denied")
+	}))
+	s.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+	s.GET("/committed", func(c *Context) {
+		c.String(http.StatusAccepted, "partial")
+		panic("boom")
+	})
+	tcs := []struct {
+		path string
+		code int
+		body string
+	}{
+		{path: "/error", code: http.StatusForbidden, body: "denied\n"},
+		{path: "/panic", code: http.StatusInternalServerError, body: "Internal Server Error\n"},
+		{path: "/committed", code: http.StatusAccepted, body: "partial"},
+		{path: "/missing", code: http.StatusNotFound, body: "404 NOT FOUND: /missing\n"},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Equal(t, tc.code, w.Code)
+		assert.Equal(t, tc.body, w.Body.String())
+	}
+}
+
+func TestServerAbort(t *testing.T) {
+	s := New()
+	s.PreMiddlewares(func(c *Context) {
+		c.String(http.StatusUnauthorized, "stop")
+		c.Abort()
+	})
+	s.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "handler")
+	})
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "stop", w.Body.String())
+}
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderContentDisposition = "Content-Disposition"
+	DispositionAttachment    = "attachment"
+	DispositionInline        = "inline"
+)
+
+// File serves the file with the conditional and Range requests support of http.ServeContent, directories are not listed.
+func (c *Context) File(name string) {
+	f, err := os.Open(name)
+	if err != nil {
+		c.Error(fileError(err))
+		return
+	}
+	c.serveFile(f)
+}
+
+// FileFromFS serves the file of fsys, typically an embed.FS.
+func (c *Context) FileFromFS(name string, fsys fs.FS) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		c.Error(fileError(err))
+		return
+	}
+	c.serveFile(f)
+}
+
+func (c *Context) serveFile(f fs.File) {
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		c.Error(fileError(err))
+		return
+	}
+	if info.IsDir() {
+		c.Error(NewHTTPError(http.StatusNotFound))
+		return
+	}
+	c.serveContent(info.Name(), info.ModTime(), f)
+}
+
+func fileError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrInvalid):
+		return NewHTTPError(http.StatusNotFound).WithError(err)
+	case errors.Is(err, fs.ErrPermission):
+		return NewHTTPError(http.StatusForbidden).WithError(err)
+	}
+	return NewHTTPError(http.StatusInternalServerError).WithError(err)
+}
+
+// Attachment sends the content as a download named filename, the content type is guessed from its extension.
+func (c *Context) Attachment(filename string, r io.Reader) {
+	c.SetHeader(HeaderContentDisposition, ContentDisposition(DispositionAttachment, filename))
+	c.serveContent(filename, time.Time{}, r)
+}
+
+// Stream sends the content, the io.ReadSeeker ones supporting Range and If-Range requests.
+func (c *Context) Stream(r io.Reader) {
+	c.serveContent("", time.Time{}, r)
+}
+
+// serveContent copies the non seekable readers as they come, without Range support.
+func (c *Context) serveContent(name string, modtime time.Time, r io.Reader) {
+	if rs, ok := r.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Req, name, modtime, rs)
+		if c.resp != nil {
+			c.StatusCode = c.resp.status
+		}
+		return
+	}
+	if c.Writer.Header().Get(HeaderContentType) == "" {
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = MIMEOctetStream
+		}
+		c.SetHeader(HeaderContentType, contentType)
+	}
+	c.Status(http.StatusOK)
+	if c.Method != http.MethodHead {
+		_, _ = io.Copy(c.Writer, r)
+	}
+}
+
+// ContentDisposition formats the header value as specified by RFC 6266, with an ASCII fallback of the filename
+// and its UTF-8 encoded form when it holds other characters.
+func ContentDisposition(disposition string, filename string) string {
+	filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	fallback, plain := asciiFilename(filename)
+	v := disposition + `; filename="` + fallback + `"`
+	if !plain {
+		v += "; filename*=UTF-8''" + encodeRFC5987(filename)
+	}
+	return v
+}
+
+// asciiFilename replaces the characters which can not be sent in a quoted string, plain reports if none was replaced.
+func asciiFilename(filename string) (fallback string, plain bool) {
+	plain = true
+	fallback = strings.Map(func(r rune) rune {
+		if r < 0x20 || r >= 0x7f || r == '"' || r == '\\' || r == '%' {
+			plain = false
+			return '_'
+		}
+		return r
+	}, filename)
+	return
+}
+
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || strings.IndexByte("!#$&+-.^_`|~", ch) >= 0 {
+			b.WriteByte(ch)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(hex[ch>>4])
+			b.WriteByte(hex[ch&0x0f])
+		}
+	}
+	return b.String()
+}
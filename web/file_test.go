@@ -0,0 +1,104 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestContentDisposition(t *testing.T) {
+	tcs := []struct {
+		filename string
+		expected string
+	}{
+		{filename: "report.pdf", expected: `attachment; filename="report.pdf"`},
+		{filename: "../../etc/passwd", expected: `attachment; filename="passwd"`},
+		{filename: `a"b.txt`, expected: `attachment; filename="a_b.txt"; filename*=UTF-8''a%22b.txt`},
+		{filename: "résumé 2024.pdf", expected: `attachment; filename="r_sum_ 2024.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9%202024.pdf`},
+	}
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expected, ContentDisposition(DispositionAttachment, tc.filename))
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "report.txt")
+	assert.NoError(t, os.WriteFile(name, []byte("0123456789"), 0o600))
+	s := New()
+	s.GET("/file", func(c *Context) {
+		c.File(name)
+	})
+	s.GET("/missing", func(c *Context) {
+		c.File(filepath.Join(dir, "missing.txt"))
+	})
+	s.GET("/dir", func(c *Context) {
+		c.File(dir)
+	})
+	s.GET("/fs", func(c *Context) {
+		c.FileFromFS("static/app.js", fstest.MapFS{"static/app.js": {Data: []byte("alert(1)")}})
+	})
+	tcs := []struct {
+		path        string
+		header      map[string]string
+		code        int
+		body        string
+		contentType string
+	}{
+		{path: "/file", code: http.StatusOK, body: "0123456789", contentType: "text/plain; charset=utf-8"},
+		{path: "/file", header: map[string]string{"Range": "bytes=2-4"}, code: http.StatusPartialContent, body: "234"},
+		{path: "/file", header: map[string]string{"Range": "bytes=20-"}, code: http.StatusRequestedRangeNotSatisfiable},
+		{path: "/file", header: map[string]string{"Range": "bytes=2-4", "If-Range": `"other"`}, code: http.StatusOK, body: "0123456789"},
+		{path: "/missing", code: http.StatusNotFound},
+		{path: "/dir", code: http.StatusNotFound},
+		{path: "/fs", code: http.StatusOK, body: "alert(1)", contentType: "text/javascript; charset=utf-8"},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		for k, v := range tc.header {
+			req.Header.Set(k, v)
+		}
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, tc.path)
+		if tc.body != "" {
+			assert.Equal(t, tc.body, w.Body.String())
+		}
+		if tc.contentType != "" {
+			assert.Equal(t, tc.contentType, w.Header().Get(HeaderContentType))
+		}
+	}
+}
+
+func TestAttachmentAndStream(t *testing.T) {
+	s := New()
+	s.GET("/report", func(c *Context) {
+		c.Attachment("report.csv", strings.NewReader("a,b\n1,2\n"))
+	})
+	s.GET("/stream", func(c *Context) {
+		c.Stream(io.MultiReader(strings.NewReader("chunk1"), strings.NewReader("chunk2")))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	req.Header.Set("Range", "bytes=4-")
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "1,2\n", w.Body.String())
+	assert.Equal(t, `attachment; filename="report.csv"`, w.Header().Get(HeaderContentDisposition))
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get(HeaderContentType))
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/stream", nil)
+	req.Header.Set("Range", "bytes=0-1")
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "chunk1chunk2", w.Body.String())
+	assert.Equal(t, MIMEOctetStream, w.Header().Get(HeaderContentType))
+}
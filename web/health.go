@@ -0,0 +1,195 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+)
+
+var ErrShuttingDown = errors.New("server is shutting down")
+
+type (
+	// HealthCheck reports an unhealthy dependency by an error, it should return once ctx is done.
+	HealthCheck func(ctx context.Context) error
+
+	HealthConfig struct {
+		// LivenessPath is "/healthz" by default.
+		LivenessPath string
+		// ReadinessPath is "/readyz" by default.
+		ReadinessPath string
+		// Timeout of every check, 5 seconds by default.
+		Timeout time.Duration
+		// CacheTTL is how long the results are reused, so that the probes can not overload the dependencies, 1 second by default.
+		CacheTTL time.Duration
+	}
+
+	// Health runs the checks of the liveness and readiness endpoints.
+	Health struct {
+		cfg       HealthConfig
+		server    *Server
+		liveness  *healthChecks
+		readiness *healthChecks
+	}
+
+	healthChecks struct {
+		names  []string
+		checks map[string]HealthCheck
+		mutex  sync.Mutex
+		report *HealthReport
+		at     time.Time
+	}
+
+	HealthReport struct {
+		Status string                       `json:"status"`
+		Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+	}
+
+	HealthCheckResult struct {
+		Status   string `json:"status"`
+		Error    string `json:"error,omitempty"`
+		Duration string `json:"duration"`
+	}
+)
+
+func (s *Server) Health() *Health {
+	return s.HealthWithConfig(HealthConfig{})
+}
+
+// HealthWithConfig registers the liveness and readiness endpoints, answering 200 if all the checks pass, 503 otherwise.
+// The readiness fails as soon as Shutdown is called, so that the load balancers stop sending requests.
+func (s *Server) HealthWithConfig(cfg HealthConfig) *Health {
+	if cfg.LivenessPath == "" {
+		cfg.LivenessPath = "/healthz"
+	}
+	if cfg.ReadinessPath == "" {
+		cfg.ReadinessPath = "/readyz"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = time.Second
+	}
+	h := &Health{
+		cfg:       cfg,
+		server:    s,
+		liveness:  &healthChecks{checks: map[string]HealthCheck{}},
+		readiness: &healthChecks{checks: map[string]HealthCheck{}},
+	}
+	s.GET(cfg.LivenessPath, func(c *Context) {
+		h.respond(c, h.liveness.run(c.Req.Context(), cfg))
+	})
+	s.GET(cfg.ReadinessPath, func(c *Context) {
+		select {
+		case <-s.ShuttingDown():
+			h.respond(c, &HealthReport{Status: HealthStatusFail, Checks: map[string]HealthCheckResult{
+				"shutdown": {Status: HealthStatusFail, Error: ErrShuttingDown.Error(), Duration: "0s"},
+			}})
+		default:
+			h.respond(c, h.readiness.run(c.Req.Context(), cfg))
+		}
+	})
+	return h
+}
+
+// AddLivenessCheck adds a check failing only when the process must be restarted, it is also a readiness check.
+func (h *Health) AddLivenessCheck(name string, check HealthCheck) *Health {
+	h.liveness.add(name, check)
+	h.readiness.add(name, check)
+	return h
+}
+
+// AddReadinessCheck adds a check failing while the server can not serve, like a database being unreachable.
+func (h *Health) AddReadinessCheck(name string, check HealthCheck) *Health {
+	h.readiness.add(name, check)
+	return h
+}
+
+func (h *Health) respond(c *Context, report *HealthReport) {
+	code := http.StatusOK
+	if report.Status != HealthStatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	c.SetHeader(HeaderCacheControl, "no-store")
+	c.JSON(code, report)
+}
+
+func (hc *healthChecks) add(name string, check HealthCheck) {
+	if check == nil {
+		panic("Health check should not be nil!")
+	}
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+	if _, ok := hc.checks[name]; ok {
+		panic(fmt.Sprintf("Duplicated health check %s!", name))
+	}
+	hc.names = append(hc.names, name)
+	hc.checks[name] = check
+	hc.report = nil
+}
+
+// run executes the checks concurrently, the concurrent probes wait for the same run rather than starting their own.
+func (hc *healthChecks) run(ctx context.Context, cfg HealthConfig) *HealthReport {
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+	if hc.report != nil && time.Since(hc.at) < cfg.CacheTTL {
+		return hc.report
+	}
+	// The results are shared, so a probe disconnecting must not cancel the checks. The context is the one of the request
+	// rather than the pooled Context, as the checks outliving the timeout keep running after the request.
+	ctx = context.WithoutCancel(ctx)
+	report := &HealthReport{Status: HealthStatusOK, Checks: make(map[string]HealthCheckResult, len(hc.names))}
+	results := make([]HealthCheckResult, len(hc.names))
+	wg := sync.WaitGroup{}
+	for i, name := range hc.names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, hc.checks[name], cfg.Timeout)
+		}()
+	}
+	wg.Wait()
+	for i, name := range hc.names {
+		if results[i].Status != HealthStatusOK {
+			report.Status = HealthStatusFail
+		}
+		report.Checks[name] = results[i]
+	}
+	hc.report, hc.at = report, time.Now()
+	return report
+}
+
+// runHealthCheck gives up on the checks ignoring their context once the timeout is reached, and recovers their panics.
+func runHealthCheck(ctx context.Context, check HealthCheck, timeout time.Duration) (result HealthCheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result = HealthCheckResult{Status: HealthStatusOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status, result.Error = HealthStatusFail, err.Error()
+	}
+	return
+}
@@ -0,0 +1,95 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func probe(t *testing.T, s *Server, path string) (code int, report HealthReport) {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "no-store", w.Header().Get(HeaderCacheControl))
+	return w.Code, report
+}
+
+func TestHealth(t *testing.T) {
+	s := New()
+	var dbErr atomic.Value
+	dbErr.Store(errors.New("connection refused"))
+	calls := atomic.Int32{}
+	s.HealthWithConfig(HealthConfig{Timeout: 20 * time.Millisecond, CacheTTL: -1}).
+		AddLivenessCheck("deadlock", func(ctx context.Context) error {
+			return nil
+		}).
+		AddReadinessCheck("db", func(ctx context.Context) error {
+			calls.Add(1)
+			err, _ := dbErr.Load().(error)
+			return err
+		}).
+		AddReadinessCheck("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).
+		AddReadinessCheck("stuck", func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}).
+		AddReadinessCheck("panic", func(ctx context.Context) error {
+			panic("boom")
+		})
+
+	code, report := probe(t, s, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthStatusOK, report.Status)
+	assert.Len(t, report.Checks, 1)
+
+	start := time.Now()
+	code, report = probe(t, s, "/readyz")
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthStatusFail, report.Status)
+	assert.Equal(t, HealthStatusOK, report.Checks["deadlock"].Status)
+	assert.Equal(t, "connection refused", report.Checks["db"].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["stuck"].Error)
+	assert.Equal(t, "panic: boom", report.Checks["panic"].Error)
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+	code, report = probe(t, s, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ErrShuttingDown.Error(), report.Checks["shutdown"].Error)
+	assert.Equal(t, int32(1), calls.Load())
+	code, _ = probe(t, s, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestHealthCache(t *testing.T) {
+	s := New()
+	calls := atomic.Int32{}
+	s.HealthWithConfig(HealthConfig{LivenessPath: "/live", ReadinessPath: "/ready", CacheTTL: time.Hour}).
+		AddReadinessCheck("db", func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		})
+	for i := 0; i < 3; i++ {
+		code, report := probe(t, s, "/ready")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, HealthStatusOK, report.Checks["db"].Status)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+	code, report := probe(t, s, "/live")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, report.Checks)
+	assert.Panics(t, func() {
+		s.Health().AddReadinessCheck("db", func(ctx context.Context) error { return nil }).
+			AddLivenessCheck("db", func(ctx context.Context) error { return nil })
+	})
+}
@@ -0,0 +1,314 @@
+package web
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgES256 = "ES256"
+)
+
+var (
+	ErrJWTMalformed = errors.New("malformed token")
+	ErrJWTAlgorithm = errors.New("unexpected signing algorithm")
+	ErrJWTKey       = errors.New("unknown signing key")
+	ErrJWTSignature = errors.New("invalid signature")
+	ErrJWTExpired   = errors.New("token is expired")
+	ErrJWTNotBefore = errors.New("token is not valid yet")
+	ErrJWTIssuer    = errors.New("invalid issuer")
+	ErrJWTAudience  = errors.New("invalid audience")
+)
+
+type (
+	JWTConfig struct {
+		// Algorithms accepted in the token header, all the supported ones by default.
+		Algorithms []string
+		// Secret is the key of HS256 tokens.
+		Secret []byte
+		// Keys by key ID, as loaded by LoadJWKS, the token without "kid" uses the only key if there is one.
+		Keys *JWKS
+		// Issuer and Audience are checked if not empty.
+		Issuer   string
+		Audience string
+		// Leeway tolerated on exp and nbf for the clock skew.
+		Leeway time.Duration
+		// Now returns the current time, time.Now by default.
+		Now func() time.Time
+	}
+
+	jwtHeader struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ"`
+	}
+
+	// JWKS maps the key IDs to the public keys (*rsa.PublicKey, *ecdsa.PublicKey) or the HMAC secrets ([]byte).
+	JWKS struct {
+		keys map[string]any
+	}
+
+	jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+		K   string `json:"k"`
+	}
+)
+
+func LoadJWKS(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+func ParseJWKS(data []byte) (ks *JWKS, err error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.Unmarshal(data, &set); err != nil {
+		return
+	}
+	ks = &JWKS{keys: make(map[string]any, len(set.Keys))}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key any
+		if key, err = k.parse(); err != nil {
+			return nil, fmt.Errorf("jwk %q: %w", k.Kid, err)
+		}
+		ks.keys[k.Kid] = key
+	}
+	return
+}
+
+func (k *jwk) parse() (key any, err error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, errN := decode(k.N)
+		e, errE := decode(k.E)
+		if err = errors.Join(errN, errE); err != nil {
+			return
+		}
+		if len(e) > 4 {
+			return nil, errors.New("rsa exponent is too large")
+		}
+		key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, errX := decode(k.X)
+		y, errY := decode(k.Y)
+		if err = errors.Join(errX, errY); err != nil {
+			return
+		}
+		key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	case "oct":
+		key, err = decode(k.K)
+	default:
+		err = fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+	return
+}
+
+func (ks *JWKS) Key(kid string) (key any, ok bool) {
+	if ks == nil {
+		return
+	}
+	if key, ok = ks.keys[kid]; !ok && kid == "" && len(ks.keys) == 1 {
+		for _, key = range ks.keys {
+			ok = true
+		}
+	}
+	return
+}
+
+// ParseJWT verifies the signature and the registered claims of the token, then returns its claims.
+func ParseJWT(token string, cfg *JWTConfig) (claims map[string]any, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+	var header jwtHeader
+	if err = decodeJWTPart(parts[0], &header); err != nil {
+		return
+	}
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{JWTAlgHS256, JWTAlgRS256, JWTAlgES256}
+	}
+	if !slices.Contains(algorithms, header.Alg) {
+		return nil, ErrJWTAlgorithm
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+	key, ok := cfg.Keys.Key(header.Kid)
+	if !ok && header.Alg == JWTAlgHS256 && cfg.Secret != nil {
+		key, ok = cfg.Secret, true
+	}
+	if !ok {
+		return nil, ErrJWTKey
+	}
+	if err = verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return
+	}
+	if err = decodeJWTPart(parts[1], &claims); err != nil {
+		return
+	}
+	now := time.Now
+	if cfg.Now != nil {
+		now = cfg.Now
+	}
+	err = validateJWTClaims(claims, cfg, now())
+	return
+}
+
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return ErrJWTMalformed
+	}
+	return nil
+}
+
+// The key type must match the algorithm, so that a public key can not be used as HMAC secret.
+func verifyJWTSignature(alg string, key any, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case JWTAlgHS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrJWTKey
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrJWTSignature
+		}
+	case JWTAlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrJWTKey
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+			return ErrJWTSignature
+		}
+	case JWTAlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrJWTKey
+		}
+		if len(signature) != 64 {
+			return ErrJWTSignature
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrJWTSignature
+		}
+	default:
+		return ErrJWTAlgorithm
+	}
+	return nil
+}
+
+func validateJWTClaims(claims map[string]any, cfg *JWTConfig, now time.Time) error {
+	if exp, ok := claims["exp"].(float64); ok && !now.Before(time.Unix(int64(exp), 0).Add(cfg.Leeway)) {
+		return ErrJWTExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(cfg.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return ErrJWTNotBefore
+	}
+	if cfg.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != cfg.Issuer {
+			return ErrJWTIssuer
+		}
+	}
+	if cfg.Audience != "" && !slices.Contains(claimStrings(claims["aud"]), cfg.Audience) {
+		return ErrJWTAudience
+	}
+	return nil
+}
+
+// claimStrings reads a claim being either a single string or an array of strings.
+func claimStrings(claim any) (values []string) {
+	switch v := claim.(type) {
+	case string:
+		values = []string{v}
+	case []any:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return
+}
+
+// JWTPrincipal builds the principal from the "sub", "roles" and "scope" (or "scp") claims.
+func JWTPrincipal(claims map[string]any) *Principal {
+	p := &Principal{Scheme: AuthSchemeBearer, Claims: claims, Roles: claimStrings(claims["roles"])}
+	p.ID, _ = claims["sub"].(string)
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = claimStrings(claims["scp"])
+	}
+	return p
+}
+
+func JWT(secret []byte) func(*Context) {
+	return JWTWithConfig(JWTConfig{Secret: secret, Algorithms: []string{JWTAlgHS256}})
+}
+
+func JWTWithConfig(cfg JWTConfig) func(*Context) {
+	if cfg.Secret == nil && cfg.Keys == nil {
+		panic("JWT secret or keys should not be nil!")
+	}
+	return func(c *Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			c.SetHeader(HeaderWWWAuthenticate, AuthSchemeBearer+` realm="`+AuthRealm+`"`)
+			c.Error(NewHTTPError(http.StatusUnauthorized))
+			return
+		}
+		claims, err := ParseJWT(token, &cfg)
+		if err != nil {
+			c.SetHeader(HeaderWWWAuthenticate, AuthSchemeBearer+` realm="`+AuthRealm+`", error="invalid_token"`)
+			c.Error(NewHTTPError(http.StatusUnauthorized).WithError(err))
+			return
+		}
+		c.SetPrincipal(JWTPrincipal(claims))
+	}
+}
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func signJWT(t *testing.T, alg string, kid string, key any, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		assert.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks := fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"rsa","n":"%s","e":"%s"},
+		{"kty":"EC","kid":"ec","crv":"P-256","x":"%s","y":"%s"},
+		{"kty":"oct","kid":"hmac","k":"%s"},
+		{"kty":"RSA","kid":"enc","use":"enc","n":"%s","e":"%s"}]}`,
+		b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		b64(ecKey.X.Bytes()), b64(ecKey.Y.Bytes()), b64([]byte("secret")),
+		b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()))
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, []byte(jwks), 0o600))
+	ks, err := LoadJWKS(path)
+	assert.NoError(t, err)
+	assert.Len(t, ks.keys, 3)
+
+	now := time.Now()
+	cfg := &JWTConfig{Keys: ks, Issuer: "sampan", Audience: "api"}
+	claims := map[string]any{"sub": "alice", "iss": "sampan", "aud": []string{"api"}, "exp": now.Add(time.Minute).Unix()}
+	tcs := []struct {
+		token string
+		err   error
+	}{
+		{token: signJWT(t, JWTAlgRS256, "rsa", rsaKey, claims)},
+		{token: signJWT(t, JWTAlgES256, "ec", ecKey, claims)},
+		{token: signJWT(t, JWTAlgHS256, "hmac", []byte("secret"), claims)},
+		{token: signJWT(t, JWTAlgHS256, "hmac", []byte("other"), claims), err: ErrJWTSignature},
+		{token: signJWT(t, JWTAlgHS256, "rsa", []byte("secret"), claims), err: ErrJWTKey},
+		{token: signJWT(t, JWTAlgRS256, "enc", rsaKey, claims), err: ErrJWTKey},
+		{token: signJWT(t, "none", "rsa", rsaKey, claims), err: ErrJWTAlgorithm},
+		{token: "abc.def", err: ErrJWTMalformed},
+	}
+	for _, tc := range tcs {
+		parsed, err := ParseJWT(tc.token, cfg)
+		assert.ErrorIs(t, err, tc.err)
+		if tc.err == nil {
+			assert.Equal(t, "alice", parsed["sub"])
+		}
+	}
+}
+
+func TestValidateJWTClaims(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cfg := &JWTConfig{Issuer: "sampan", Audience: "api", Leeway: 10 * time.Second}
+	tcs := []struct {
+		claims map[string]any
+		err    error
+	}{
+		{claims: map[string]any{"iss": "sampan", "aud": "api", "exp": 1005.0, "nbf": 1005.0}},
+		{claims: map[string]any{"iss": "sampan", "aud": "api", "exp": 980.0}, err: ErrJWTExpired},
+		{claims: map[string]any{"iss": "sampan", "aud": "api", "nbf": 1020.0}, err: ErrJWTNotBefore},
+		{claims: map[string]any{"iss": "other", "aud": "api"}, err: ErrJWTIssuer},
+		{claims: map[string]any{"iss": "sampan", "aud": []any{"web"}}, err: ErrJWTAudience},
+	}
+	for _, tc := range tcs {
+		assert.ErrorIs(t, validateJWTClaims(tc.claims, cfg, now), tc.err)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("secret")
+	s := New()
+	g := s.Group("/api").PreMiddlewares(JWT(secret))
+	g.GET("/me", func(c *Context) {
+		c.JSON(http.StatusOK, map[string]any{"id": c.Principal().ID, "roles": c.Principal().Roles, "scopes": c.Principal().Scopes, "sub": c.Claims()["sub"]})
+	})
+	tcs := []struct {
+		authorization string
+		code          int
+		body          string
+	}{
+		{authorization: "Bearer " + signJWT(t, JWTAlgHS256, "", secret, map[string]any{"sub": "alice", "roles": []string{"admin"}, "scope": "read write"}),
+			code: http.StatusOK, body: `{"id":"alice","roles":["admin"],"scopes":["read","write"],"sub":"alice"}`},
+		{authorization: "Bearer " + signJWT(t, JWTAlgHS256, "", secret, map[string]any{"sub": "alice", "exp": time.Now().Add(-time.Minute).Unix()}),
+			code: http.StatusUnauthorized},
+		{authorization: "Basic abc", code: http.StatusUnauthorized},
+		{code: http.StatusUnauthorized},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.Header.Set(HeaderAuthorization, tc.authorization)
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code)
+		if tc.code == http.StatusOK {
+			assert.JSONEq(t, tc.body, w.Body.String())
+		} else {
+			assert.Contains(t, w.Header().Get(HeaderWWWAuthenticate), AuthSchemeBearer)
+		}
+	}
+}
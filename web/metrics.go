@@ -0,0 +1,78 @@
+package web
+
+import (
+	"github.com/ywang2728/sampan/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type MetricsConfig struct {
+	Registry *metrics.Registry
+	// Namespace prefixes the metric names, "http" by default.
+	Namespace string
+	// Buckets of the latency histogram in seconds, metrics.DefaultBuckets by default.
+	Buckets []float64
+}
+
+func Metrics(registry *metrics.Registry) func(*Context) {
+	return MetricsWithConfig(MetricsConfig{Registry: registry})
+}
+
+// MetricsWithConfig counts the requests, their latency and the ones in flight by method, route pattern and status class.
+// The pattern rather than the path is the label, so that the path parameters do not create a series each.
+func MetricsWithConfig(cfg MetricsConfig) func(*Context) {
+	if cfg.Registry == nil {
+		panic("Metrics registry should not be nil!")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = "http"
+	}
+	requests := cfg.Registry.NewCounterVec(cfg.Namespace+"_requests_total",
+		"Number of the finished requests.", "method", "route", "status")
+	durations := cfg.Registry.NewHistogramVec(cfg.Namespace+"_request_duration_seconds",
+		"Duration of the requests in seconds.", cfg.Buckets, "method", "route", "status")
+	inFlight := cfg.Registry.NewGaugeVec(cfg.Namespace+"_requests_in_flight",
+		"Number of the requests being served.", "method", "route")
+	return func(c *Context) {
+		start := time.Now()
+		method, route := c.Method, c.Pattern()
+		gauge := inFlight.WithLabelValues(method, route)
+		gauge.Inc()
+		c.Defer(func() {
+			gauge.Dec()
+			status := statusClass(c.responseStatus())
+			requests.WithLabelValues(method, route, status).Inc()
+			durations.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// statusClass groups the status codes by hundreds, like "2xx".
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// RegisterMetrics exposes the hits and misses of the router path cache, and the number of routes.
+func (s *Server) RegisterMetrics(registry *metrics.Registry) {
+	registry.NewCounterFunc("router_cache_hits_total", "Number of the paths resolved from the router cache.", func() float64 {
+		hits, _ := s.rg.router.cacheStats()
+		return float64(hits)
+	})
+	registry.NewCounterFunc("router_cache_misses_total", "Number of the paths resolved from the router tree.", func() float64 {
+		_, misses := s.rg.router.cacheStats()
+		return float64(misses)
+	})
+	registry.NewGaugeFunc("router_routes", "Number of the registered routes.", func() float64 {
+		return float64(s.rg.router.len())
+	})
+}
+
+// MetricsHandler writes the metrics of the registry in the Prometheus text format.
+func MetricsHandler(registry *metrics.Registry) func(*Context) {
+	return func(c *Context) {
+		c.SetHeader(HeaderContentType, metrics.ContentType)
+		c.Status(http.StatusOK)
+		_, _ = registry.WriteTo(c.Writer)
+	}
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/ywang2728/sampan/metrics"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	s := New()
+	s.RegisterMetrics(registry)
+	api := s.Group("/api").PreMiddlewares(Metrics(registry))
+	api.GET(`/users/{(?P<id>\d+)}`, func(c *Context) {
+		c.String(http.StatusOK, "user %s", c.params["id"])
+	})
+	api.GET("/fail", func(c *Context) {
+		panic("boom")
+	})
+	s.GET("/metrics", MetricsHandler(registry))
+	for _, path := range []string{"/api/users/1", "/api/users/2", "/api/users/1", "/api/fail", "/missing"} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, metrics.ContentType, w.Header().Get(HeaderContentType))
+	body := w.Body.String()
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/api/fail",status="5xx"} 1`,
+		`http_requests_total{method="GET",route="/api/users/{(?P<id>\\d+)}",status="2xx"} 3`,
+		`http_request_duration_seconds_count{method="GET",route="/api/users/{(?P<id>\\d+)}",status="2xx"} 3`,
+		`http_requests_in_flight{method="GET",route="/api/users/{(?P<id>\\d+)}"} 0`,
+		"router_cache_hits_total 1",
+		"router_cache_misses_total 5",
+		"router_routes 3",
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, "/api/users/1")
+	assert.False(t, strings.Contains(body, "/missing"))
+}
+
+func TestMetricsBufferedStatus(t *testing.T) {
+	registry := metrics.NewRegistry()
+	s := New()
+	// the compressed writer still holds the response back when the metrics are recorded.
+	s.PreMiddlewares(Compress(), Metrics(registry))
+	s.GET("/created", func(c *Context) { c.String(http.StatusCreated, "created") })
+	s.GET("/error", HandleError(func(c *Context) error {
+		c.String(http.StatusOK, "partial")
+		return NewHTTPError(http.StatusConflict)
+	}))
+	for _, path := range []string{"/created", "/error"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(HeaderAcceptEncoding, "gzip")
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	w := httptest.NewRecorder()
+	_, _ = registry.WriteTo(w)
+	assert.Contains(t, w.Body.String(), `http_requests_total{method="GET",route="/created",status="2xx"} 1`+"\n")
+	assert.Contains(t, w.Body.String(), `http_requests_total{method="GET",route="/error",status="4xx"} 1`+"\n")
+}
+
+func TestRegisterMetricsConcurrentRoutes(t *testing.T) {
+	registry := metrics.NewRegistry()
+	s := New()
+	s.RegisterMetrics(registry)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch} {
+			s.PutRoute(method, "/r", func(c *Context) {})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_, _ = registry.WriteTo(io.Discard)
+	}
+	<-done
+	w := httptest.NewRecorder()
+	_, _ = registry.WriteTo(w)
+	assert.Contains(t, w.Body.String(), "router_routes 4\n")
+}
@@ -0,0 +1,450 @@
+package web
+
+import (
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const OpenAPIVersion = "3.1.0"
+
+var schemaNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+type (
+	// schemaSet collects the schemas of the named structs, by the names given to their types.
+	schemaSet struct {
+		schemas map[string]*Schema
+		names   map[reflect.Type]string
+	}
+
+	// routeDoc is the optional metadata of a route, documented by the OpenAPI document.
+	routeDoc struct {
+		summary     string
+		description string
+		operationID string
+		tags        []string
+		deprecated  bool
+		hidden      bool
+		request     reflect.Type
+		query       reflect.Type
+		responses   map[int]reflect.Type
+	}
+
+	OpenAPIInfo struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description,omitempty"`
+	}
+
+	OpenAPIDocument struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       OpenAPIInfo                             `json:"info"`
+		Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+		Components *OpenAPIComponents                      `json:"components,omitempty"`
+	}
+
+	OpenAPIComponents struct {
+		Schemas map[string]*Schema `json:"schemas,omitempty"`
+	}
+
+	OpenAPIOperation struct {
+		OperationID string                      `json:"operationId,omitempty"`
+		Summary     string                      `json:"summary,omitempty"`
+		Description string                      `json:"description,omitempty"`
+		Tags        []string                    `json:"tags,omitempty"`
+		Deprecated  bool                        `json:"deprecated,omitempty"`
+		Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*OpenAPIResponse `json:"responses"`
+	}
+
+	OpenAPIParameter struct {
+		Name        string  `json:"name"`
+		In          string  `json:"in"`
+		Description string  `json:"description,omitempty"`
+		Required    bool    `json:"required,omitempty"`
+		Schema      *Schema `json:"schema"`
+	}
+
+	OpenAPIRequestBody struct {
+		Required bool                         `json:"required,omitempty"`
+		Content  map[string]*OpenAPIMediaType `json:"content"`
+	}
+
+	OpenAPIResponse struct {
+		Description string                       `json:"description"`
+		Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+	}
+
+	OpenAPIMediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+
+	// Schema is the subset of JSON Schema describing the Go types.
+	Schema struct {
+		Ref                  string             `json:"$ref,omitempty"`
+		Type                 string             `json:"type,omitempty"`
+		Format               string             `json:"format,omitempty"`
+		ContentEncoding      string             `json:"contentEncoding,omitempty"`
+		Description          string             `json:"description,omitempty"`
+		Pattern              string             `json:"pattern,omitempty"`
+		Minimum              *float64           `json:"minimum,omitempty"`
+		Items                *Schema            `json:"items,omitempty"`
+		Properties           map[string]*Schema `json:"properties,omitempty"`
+		AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+		Required             []string           `json:"required,omitempty"`
+	}
+)
+
+func (r *Route) Summary(summary string) *Route {
+	r.doc.summary = summary
+	return r
+}
+
+func (r *Route) Description(description string) *Route {
+	r.doc.description = description
+	return r
+}
+
+func (r *Route) OperationID(id string) *Route {
+	r.doc.operationID = id
+	return r
+}
+
+func (r *Route) Tags(tags ...string) *Route {
+	r.doc.tags = append(r.doc.tags, tags...)
+	return r
+}
+
+func (r *Route) Deprecated() *Route {
+	r.doc.deprecated = true
+	return r
+}
+
+// Hide leaves the route out of the OpenAPI document.
+func (r *Route) Hide() *Route {
+	r.doc.hidden = true
+	return r
+}
+
+// Request documents the JSON body of the request by the type of v, its exported fields named by their json tag.
+func (r *Route) Request(v any) *Route {
+	r.doc.request = reflect.TypeOf(v)
+	return r
+}
+
+// Query documents the query parameters by the fields of the struct v, named by their query tag, like `query:"page,required"`.
+func (r *Route) Query(v any) *Route {
+	r.doc.query = reflect.TypeOf(v)
+	return r
+}
+
+// Response documents the JSON body of the response with the code by the type of v, without body if nil.
+func (r *Route) Response(code int, v any) *Route {
+	if r.doc.responses == nil {
+		r.doc.responses = map[int]reflect.Type{}
+	}
+	r.doc.responses[code] = reflect.TypeOf(v)
+	return r
+}
+
+// OpenAPI generates the document of the registered routes, the struct types being described in the components.
+func (s *Server) OpenAPI(info OpenAPIInfo) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI:    OpenAPIVersion,
+		Info:       info,
+		Paths:      map[string]map[string]*OpenAPIOperation{},
+		Components: &OpenAPIComponents{Schemas: map[string]*Schema{}},
+	}
+	schemas := &schemaSet{schemas: doc.Components.Schemas, names: map[reflect.Type]string{}}
+	for _, route := range s.Routes() {
+		if route.doc.hidden || route.Method == http.MethodConnect {
+			continue
+		}
+		path, params := openAPIPath(route.Pattern)
+		op := &OpenAPIOperation{
+			OperationID: route.doc.operationID,
+			Summary:     route.doc.summary,
+			Description: route.doc.description,
+			Tags:        route.doc.tags,
+			Deprecated:  route.doc.deprecated,
+			Parameters:  params,
+			Responses:   map[string]*OpenAPIResponse{},
+		}
+		if route.doc.query != nil {
+			op.Parameters = append(op.Parameters, queryParameters(route.doc.query, schemas)...)
+		}
+		if route.doc.request != nil {
+			op.RequestBody = &OpenAPIRequestBody{Required: true, Content: map[string]*OpenAPIMediaType{
+				MIMEApplicationJSON: {Schema: schemaOf(route.doc.request, schemas)},
+			}}
+		}
+		for code, t := range route.doc.responses {
+			resp := &OpenAPIResponse{Description: http.StatusText(code)}
+			if t != nil {
+				resp.Content = map[string]*OpenAPIMediaType{MIMEApplicationJSON: {Schema: schemaOf(t, schemas)}}
+			}
+			op.Responses[strconv.Itoa(code)] = resp
+		}
+		if len(op.Responses) == 0 {
+			op.Responses["200"] = &OpenAPIResponse{Description: http.StatusText(http.StatusOK)}
+		}
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*OpenAPIOperation{}
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+	if len(doc.Components.Schemas) == 0 {
+		doc.Components = nil
+	}
+	return doc
+}
+
+// ServeOpenAPI serves the document on the path, as YAML if the path ends with ".yaml" or if the client prefers it, as JSON otherwise.
+func (s *Server) ServeOpenAPI(path string, info OpenAPIInfo) *Route {
+	return s.GET(path, func(c *Context) {
+		doc := s.OpenAPI(info)
+		if strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") ||
+			!strings.HasSuffix(path, ".json") && c.NegotiateFormat(MIMEApplicationJSON, MIMEApplicationYAML) == MIMEApplicationYAML {
+			b, err := doc.YAML()
+			if err != nil {
+				c.Error(NewHTTPError(http.StatusInternalServerError).WithError(err))
+				return
+			}
+			c.write(http.StatusOK, MIMEApplicationYAML+"; charset=utf-8", b)
+			return
+		}
+		c.IndentedJSON(http.StatusOK, doc)
+	}).Hide()
+}
+
+// YAML converts the JSON form of the document, so that both share the names of the json tags.
+func (doc *OpenAPIDocument) YAML() ([]byte, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err = json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// openAPIPath turns the regex segments of the pattern into templated path parameters, the named groups of the
+// expressions naming the parameters, like "{(?P<id>\d+)}" becoming "{id}" with the pattern "^\d+$".
+func openAPIPath(pattern string) (path string, params []*OpenAPIParameter) {
+	b := strings.Builder{}
+	for _, p := range parseRePatterns(pattern) {
+		if p.compiled == nil {
+			b.WriteString(p.raw)
+			continue
+		}
+		re, err := syntax.Parse(p.compiled.String(), syntax.Perl)
+		if err != nil {
+			continue
+		}
+		parts := []*syntax.Regexp{re}
+		if re.Op == syntax.OpConcat {
+			parts = re.Sub
+		}
+		template, sources := strings.Builder{}, groupSources(p.compiled.String())
+		var named []*OpenAPIParameter
+		for _, part := range parts {
+			if part.Op == syntax.OpCapture && part.Name != "" {
+				template.WriteString("{" + part.Name + "}")
+				named = append(named, pathParameter(part.Name, sources[part.Name]))
+			} else if part.Op == syntax.OpLiteral && part.Flags&syntax.FoldCase == 0 {
+				template.WriteString(string(part.Rune))
+			} else {
+				named = nil
+				break
+			}
+		}
+		// The expressions which can not be split into named groups and literals are documented as a single parameter.
+		if named == nil {
+			name := "param" + strconv.Itoa(len(params)+1)
+			template.Reset()
+			template.WriteString("{" + name + "}")
+			named = []*OpenAPIParameter{pathParameter(name, p.compiled.String())}
+		}
+		b.WriteString(template.String())
+		params = append(params, named...)
+	}
+	return b.String(), params
+}
+
+// groupSources maps the names of the top level groups of the expression to their source, as the parsed expressions are
+// printed in the Go syntax, like "\\d" becoming "[0-9]".
+func groupSources(expr string) (sources map[string]string) {
+	sources = map[string]string{}
+	depth, start, name, class := 0, 0, "", false
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\\':
+			i++
+		case class:
+			class = c != ']'
+		case c == '[':
+			class = true
+		case c == '(':
+			if depth++; depth == 1 {
+				name, start = "", i+1
+				for _, prefix := range []string{"(?P<", "(?<"} {
+					if strings.HasPrefix(expr[i:], prefix) {
+						if end := strings.IndexByte(expr[i:], '>'); end != -1 {
+							name, start = expr[i+len(prefix):i+end], i+end+1
+						}
+					}
+				}
+			}
+		case c == ')':
+			if depth--; depth == 0 && name != "" {
+				sources[name] = expr[start:i]
+			}
+		}
+	}
+	return
+}
+
+// pathParameter anchors the pattern as a group, so that the anchors apply to every branch of an alternation.
+func pathParameter(name string, pattern string) *OpenAPIParameter {
+	return &OpenAPIParameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string", Pattern: "^(?:" + pattern + ")$"}}
+}
+
+func queryParameters(t reflect.Type, schemas *schemaSet) (params []*OpenAPIParameter) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		tag, opts, _ := strings.Cut(f.Tag.Get("query"), ",")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		params = append(params, &OpenAPIParameter{
+			Name:        tag,
+			In:          "query",
+			Description: f.Tag.Get("doc"),
+			Required:    slices.Contains(strings.Split(opts, ","), "required"),
+			Schema:      schemaOf(f.Type, schemas),
+		})
+	}
+	return
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf describes the type, the named structs being added to the schemas and referenced, which also ends the recursion.
+func schemaOf(t reflect.Type, schemas *schemaSet) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		minimum := 0.0
+		return &Schema{Type: "integer", Minimum: &minimum}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+		name, ok := schemas.names[t]
+		if !ok {
+			name = schemas.name(t)
+			schemas.names[t] = name
+			schemas.schemas[name] = &Schema{}
+			*schemas.schemas[name] = *structSchema(t, schemas)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// name is the name of the type, qualified with its package if another type has taken it, then numbered if that is not enough,
+// like for the instances of a generic type.
+func (ss *schemaSet) name(t reflect.Type) string {
+	name := schemaNameRe.ReplaceAllString(t.Name(), "_")
+	if _, ok := ss.schemas[name]; !ok {
+		return name
+	}
+	qualified := schemaNameRe.ReplaceAllString(t.PkgPath()+"."+t.Name(), "_")
+	name = qualified
+	for i := 2; ; i++ {
+		if _, ok := ss.schemas[name]; !ok {
+			return name
+		}
+		name = qualified + "_" + strconv.Itoa(i)
+	}
+}
+
+// structSchema describes the exported fields by their json names, the ones without omitempty nor pointer being required.
+func structSchema(t reflect.Type, schemas *schemaSet) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, f := range reflect.VisibleFields(t) {
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		// The fields of the untagged embedded structs are promoted, as encoding/json flattens them.
+		if !f.IsExported() || f.Anonymous && f.Tag.Get("json") == "" && ft.Kind() == reflect.Struct {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := schemaOf(f.Type, schemas)
+		if doc := f.Tag.Get("doc"); doc != "" {
+			if fs.Ref != "" {
+				// The siblings of $ref are allowed by OpenAPI 3.1, describing the field rather than its type.
+				fs = &Schema{Ref: fs.Ref}
+			}
+			fs.Description = doc
+		}
+		s.Properties[name] = fs
+		if !slices.Contains(strings.Split(opts, ","), "omitempty") && f.Type.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
@@ -0,0 +1,163 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type (
+	openAPIBase struct {
+		ID      int64     `json:"id"`
+		Created time.Time `json:"created"`
+	}
+
+	openAPIUser struct {
+		openAPIBase
+		Name    string            `json:"name" doc:"display name"`
+		Email   *string           `json:"email"`
+		Age     uint8             `json:"age,omitempty"`
+		Avatar  []byte            `json:"avatar,omitempty"`
+		Labels  map[string]string `json:"labels,omitempty"`
+		Friends []*openAPIUser    `json:"friends,omitempty"`
+		secret  string
+		Ignored string `json:"-"`
+	}
+
+	openAPIPage struct {
+		Page  int    `query:"page,required"`
+		Order string `query:"order" doc:"asc or desc"`
+	}
+)
+
+func TestOpenAPIPath(t *testing.T) {
+	tcs := []struct {
+		pattern string
+		path    string
+		params  []*OpenAPIParameter
+	}{
+		{pattern: "/users", path: "/users"},
+		{pattern: `/users/{(?P<id>\d+)}`, path: "/users/{id}", params: []*OpenAPIParameter{pathParameter("id", `\d+`)}},
+		{pattern: `/files/{(?P<name>[a-z]+)\.(?P<ext>[a-z]{3})}/raw`, path: "/files/{name}.{ext}/raw",
+			params: []*OpenAPIParameter{pathParameter("name", "[a-z]+"), pathParameter("ext", "[a-z]{3}")}},
+		{pattern: `/a/{\d+}/b/{[a-z]+|-}`, path: "/a/{param1}/b/{param2}",
+			params: []*OpenAPIParameter{pathParameter("param1", `\d+`), {Name: "param2", In: "path", Required: true,
+				Schema: &Schema{Type: "string", Pattern: "^(?:[a-z]+|-)$"}}}},
+	}
+	for _, tc := range tcs {
+		path, params := openAPIPath(tc.pattern)
+		assert.Equal(t, tc.path, path, tc.pattern)
+		assert.Equal(t, tc.params, params, tc.pattern)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	s := New()
+	s.GET(`/users/{(?P<id>\d+)}`, func(c *Context) {}).
+		Summary("Get a user").Tags("users").OperationID("getUser").
+		Response(http.StatusOK, openAPIUser{}).Response(http.StatusNotFound, nil)
+	s.GET("/users", func(c *Context) {}).Query(openAPIPage{}).Response(http.StatusOK, []openAPIUser{})
+	s.POST("/users", func(c *Context) {}).Request(&openAPIUser{}).Response(http.StatusCreated, openAPIUser{}).Deprecated()
+	s.DELETE("/internal", func(c *Context) {}).Hide()
+	s.rg.MountDebug("/debug", nil)
+	s.Mount("/legacy", http.NotFoundHandler())
+
+	doc := s.OpenAPI(OpenAPIInfo{Title: "API", Version: "1.0.0"})
+	assert.Equal(t, OpenAPIVersion, doc.OpenAPI)
+	assert.Len(t, doc.Paths, 2)
+
+	get := doc.Paths["/users/{id}"]["get"]
+	assert.Equal(t, "getUser", get.OperationID)
+	assert.Equal(t, []string{"users"}, get.Tags)
+	assert.Equal(t, []*OpenAPIParameter{pathParameter("id", `\d+`)}, get.Parameters)
+	assert.Equal(t, "#/components/schemas/openAPIUser", get.Responses["200"].Content[MIMEApplicationJSON].Schema.Ref)
+	assert.Equal(t, &OpenAPIResponse{Description: "Not Found"}, get.Responses["404"])
+
+	list := doc.Paths["/users"]["get"]
+	assert.Equal(t, []*OpenAPIParameter{
+		{Name: "page", In: "query", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}},
+		{Name: "order", In: "query", Description: "asc or desc", Schema: &Schema{Type: "string"}},
+	}, list.Parameters)
+	assert.Equal(t, "array", list.Responses["200"].Content[MIMEApplicationJSON].Schema.Type)
+
+	post := doc.Paths["/users"]["post"]
+	assert.True(t, post.Deprecated)
+	assert.True(t, post.RequestBody.Required)
+	assert.Equal(t, "#/components/schemas/openAPIUser", post.RequestBody.Content[MIMEApplicationJSON].Schema.Ref)
+
+	user := doc.Components.Schemas["openAPIUser"]
+	assert.Equal(t, []string{"id", "created", "name"}, user.Required)
+	assert.ElementsMatch(t, []string{"id", "created", "name", "email", "age", "avatar", "labels", "friends"}, keys(user.Properties))
+	assert.Equal(t, &Schema{Type: "integer", Format: "int64"}, user.Properties["id"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, user.Properties["created"])
+	assert.Equal(t, &Schema{Type: "string", Description: "display name"}, user.Properties["name"])
+	assert.Equal(t, "base64", user.Properties["avatar"].ContentEncoding)
+	assert.Equal(t, 0.0, *user.Properties["age"].Minimum)
+	assert.Equal(t, &Schema{Type: "string"}, user.Properties["labels"].AdditionalProperties)
+	assert.Equal(t, "#/components/schemas/openAPIUser", user.Properties["friends"].Items.Ref)
+}
+
+func keys(m map[string]*Schema) (ks []string) {
+	for k := range m {
+		ks = append(ks, k)
+	}
+	return
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	s := New()
+	s.GET("/ping", func(c *Context) {}).Summary("Ping")
+	s.ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "API", Version: "1.0.0"})
+	s.ServeOpenAPI("/openapi.yaml", OpenAPIInfo{Title: "API", Version: "1.0.0"})
+	s.ServeOpenAPI("/docs", OpenAPIInfo{Title: "API", Version: "1.0.0"})
+	tcs := []struct {
+		path        string
+		accept      string
+		contentType string
+	}{
+		{path: "/openapi.json", accept: MIMEApplicationYAML, contentType: MIMEApplicationJSON},
+		{path: "/openapi.yaml", contentType: MIMEApplicationYAML},
+		{path: "/docs", contentType: MIMEApplicationJSON},
+		{path: "/docs", accept: MIMEApplicationYAML, contentType: MIMEApplicationYAML},
+	}
+	for _, tc := range tcs {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Accept", tc.accept)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, tc.path)
+		assert.Contains(t, w.Header().Get(HeaderContentType), tc.contentType, tc.path)
+		var doc map[string]any
+		if tc.contentType == MIMEApplicationYAML {
+			assert.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &doc), tc.path)
+		} else {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc), tc.path)
+		}
+		assert.Equal(t, OpenAPIVersion, doc["openapi"], tc.path)
+		// The documentation routes are not documented.
+		assert.Equal(t, map[string]any{"/ping": map[string]any{"get": map[string]any{
+			"summary": "Ping", "responses": map[string]any{"200": map[string]any{"description": "OK"}},
+		}}}, doc["paths"], tc.path)
+	}
+}
+
+func TestOpenAPISchemaNames(t *testing.T) {
+	type URL struct {
+		Raw string `json:"raw"`
+	}
+	s := New()
+	s.GET("/a", func(c *Context) {}).Response(http.StatusOK, url.URL{})
+	s.GET("/b", func(c *Context) {}).Response(http.StatusOK, URL{}).Request(url.URL{})
+	doc := s.OpenAPI(OpenAPIInfo{Title: "API", Version: "1.0.0"})
+	qualified := "github.com_ywang2728_sampan_web.URL"
+	assert.ElementsMatch(t, []string{"URL", "Userinfo", qualified}, keys(doc.Components.Schemas))
+	assert.Equal(t, "#/components/schemas/URL", doc.Paths["/a"]["get"].Responses["200"].Content[MIMEApplicationJSON].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/"+qualified, doc.Paths["/b"]["get"].Responses["200"].Content[MIMEApplicationJSON].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/URL", doc.Paths["/b"]["get"].RequestBody.Content[MIMEApplicationJSON].Schema.Ref)
+	assert.Contains(t, doc.Components.Schemas[qualified].Properties, "raw")
+}
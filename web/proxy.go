@@ -0,0 +1,184 @@
+package web
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+const (
+	HeaderForwarded       = "Forwarded"
+	HeaderXForwardedFor   = "X-Forwarded-For"
+	HeaderXForwardedProto = "X-Forwarded-Proto"
+	HeaderXForwardedHost  = "X-Forwarded-Host"
+	HeaderXRealIP         = "X-Real-IP"
+)
+
+// forwardedElement is a hop of the Forwarded header of RFC 7239.
+type forwardedElement struct {
+	addr  netip.Addr
+	proto string
+	host  string
+}
+
+// SetTrustedProxies sets the CIDRs, or single addresses, of the proxies whose forwarding headers are honored.
+func (s *Server) SetTrustedProxies(cidrs ...string) error {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, errAddr := netip.ParseAddr(cidr)
+			if errAddr != nil {
+				return err
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	s.trustedProxies = prefixes
+	return nil
+}
+
+func (s *Server) trusts(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseNodeAddr(node string) (addr netip.Addr, ok bool) {
+	node = strings.TrimSpace(node)
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+	node = strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+	addr, err := netip.ParseAddr(node)
+	if err != nil {
+		return
+	}
+	return addr.Unmap(), true
+}
+
+func (c *Context) remoteAddr() (addr netip.Addr, ok bool) {
+	return parseNodeAddr(c.Req.RemoteAddr)
+}
+
+// fromTrustedProxy reports if the request comes from a trusted proxy, only then the forwarding headers are read.
+func (c *Context) fromTrustedProxy() bool {
+	addr, ok := c.remoteAddr()
+	return ok && c.server != nil && c.server.trusts(addr)
+}
+
+func parseForwarded(values []string) (elements []forwardedElement) {
+	for _, value := range values {
+		for _, e := range strings.Split(value, ",") {
+			var element forwardedElement
+			for _, pair := range strings.Split(e, ";") {
+				k, v, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if !found {
+					continue
+				}
+				v = strings.Trim(v, `"`)
+				switch strings.ToLower(k) {
+				case "for":
+					element.addr, _ = parseNodeAddr(v)
+				case "proto":
+					element.proto = strings.ToLower(v)
+				case "host":
+					element.host = v
+				}
+			}
+			elements = append(elements, element)
+		}
+	}
+	return
+}
+
+// forwardedClient walks the hops from the nearest one, skipping the trusted proxies, the first other one is the client.
+// The hops which are not IP addresses, like "unknown" or obfuscated identifiers, end the walk.
+func (c *Context) forwardedClient() (element forwardedElement, ok bool) {
+	if values := c.Req.Header.Values(HeaderForwarded); len(values) > 0 {
+		return c.lastUntrusted(parseForwarded(values))
+	}
+	if values := c.Req.Header.Values(HeaderXForwardedFor); len(values) > 0 {
+		var elements []forwardedElement
+		for _, value := range values {
+			for _, node := range strings.Split(value, ",") {
+				addr, _ := parseNodeAddr(node)
+				elements = append(elements, forwardedElement{addr: addr})
+			}
+		}
+		return c.lastUntrusted(elements)
+	}
+	if addr, found := parseNodeAddr(c.Req.Header.Get(HeaderXRealIP)); found {
+		return forwardedElement{addr: addr}, true
+	}
+	return
+}
+
+func (c *Context) lastUntrusted(elements []forwardedElement) (element forwardedElement, ok bool) {
+	for i := len(elements) - 1; i >= 0; i-- {
+		element = elements[i]
+		if !element.addr.IsValid() {
+			return element, false
+		}
+		if !c.server.trusts(element.addr) || i == 0 {
+			return element, true
+		}
+	}
+	return
+}
+
+// ClientIP returns the address of the client, read from Forwarded, X-Forwarded-For or X-Real-IP
+// when the request comes through the trusted proxies, from the connection otherwise.
+func (c *Context) ClientIP() string {
+	if c.fromTrustedProxy() {
+		if element, ok := c.forwardedClient(); ok {
+			return element.addr.String()
+		}
+	}
+	if addr, ok := c.remoteAddr(); ok {
+		return addr.String()
+	}
+	return c.Req.RemoteAddr
+}
+
+// Scheme returns "https" or "http" as seen by the client, honoring Forwarded and X-Forwarded-Proto from the trusted proxies.
+func (c *Context) Scheme() string {
+	if c.fromTrustedProxy() {
+		if c.Req.Header.Get(HeaderForwarded) != "" {
+			if element, _ := c.forwardedClient(); element.proto != "" {
+				return element.proto
+			}
+		} else if proto := lastListValue(c.Req.Header.Get(HeaderXForwardedProto)); proto != "" {
+			return strings.ToLower(proto)
+		}
+	}
+	if c.Req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host returns the host requested by the client, honoring Forwarded and X-Forwarded-Host from the trusted proxies.
+func (c *Context) Host() string {
+	if c.fromTrustedProxy() {
+		if c.Req.Header.Get(HeaderForwarded) != "" {
+			if element, _ := c.forwardedClient(); element.host != "" {
+				return element.host
+			}
+		} else if host := lastListValue(c.Req.Header.Get(HeaderXForwardedHost)); host != "" {
+			return host
+		}
+	}
+	return c.Req.Host
+}
+
+// lastListValue returns the value appended by the nearest proxy to a comma separated header.
+func lastListValue(v string) string {
+	i := strings.LastIndexByte(v, ',')
+	return strings.TrimSpace(v[i+1:])
+}
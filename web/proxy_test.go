@@ -0,0 +1,62 @@
+package web
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetTrustedProxies(t *testing.T) {
+	s := New()
+	assert.NoError(t, s.SetTrustedProxies("10.0.0.0/8", "192.168.1.1", "fd00::/8"))
+	assert.Len(t, s.trustedProxies, 3)
+	assert.Error(t, s.SetTrustedProxies("not an address"))
+}
+
+func TestClientIP(t *testing.T) {
+	s := New()
+	assert.NoError(t, s.SetTrustedProxies("10.0.0.0/8", "fd00::/8"))
+	tcs := []struct {
+		remote   string
+		header   map[string]string
+		clientIP string
+		scheme   string
+		host     string
+	}{
+		{remote: "203.0.113.7:1234", clientIP: "203.0.113.7", scheme: "http", host: "example.com"},
+		{remote: "203.0.113.7:1234", header: map[string]string{HeaderXForwardedFor: "1.2.3.4", HeaderXForwardedProto: "https", HeaderXForwardedHost: "evil.com"},
+			clientIP: "203.0.113.7", scheme: "http", host: "example.com"},
+		{remote: "10.0.0.1:1234", header: map[string]string{HeaderXForwardedFor: "1.2.3.4, 198.51.100.2, 10.0.0.2", HeaderXForwardedProto: "https", HeaderXForwardedHost: "api.example.com"},
+			clientIP: "198.51.100.2", scheme: "https", host: "api.example.com"},
+		{remote: "10.0.0.1:1234", header: map[string]string{HeaderXForwardedFor: "10.0.0.3, 10.0.0.2"}, clientIP: "10.0.0.3", scheme: "http", host: "example.com"},
+		{remote: "10.0.0.1:1234", header: map[string]string{HeaderXRealIP: "198.51.100.9"}, clientIP: "198.51.100.9", scheme: "http", host: "example.com"},
+		{remote: "10.0.0.1:1234", header: map[string]string{HeaderXForwardedFor: "garbage"}, clientIP: "10.0.0.1", scheme: "http", host: "example.com"},
+		{remote: "10.0.0.1:1234", header: map[string]string{HeaderForwarded: `for=192.0.2.60;proto=https;host=shop.example.com, for="[fd00::1]:4711";proto=http`, HeaderXForwardedFor: "1.2.3.4"},
+			clientIP: "192.0.2.60", scheme: "https", host: "shop.example.com"},
+		{remote: "[fd00::2]:443", header: map[string]string{HeaderForwarded: `for="[2001:db8:cafe::17]:4711"`}, clientIP: "2001:db8:cafe::17", scheme: "http", host: "example.com"},
+		{remote: "10.0.0.1:1234", header: map[string]string{HeaderForwarded: `for=unknown;proto=https`}, clientIP: "10.0.0.1", scheme: "https", host: "example.com"},
+	}
+	for _, tc := range tcs {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		req.RemoteAddr = tc.remote
+		for k, v := range tc.header {
+			req.Header.Set(k, v)
+		}
+		c := newContext(nil, req)
+		c.server = s
+		assert.Equal(t, tc.clientIP, c.ClientIP(), tc.header)
+		assert.Equal(t, tc.scheme, c.Scheme(), tc.header)
+		assert.Equal(t, tc.host, c.Host(), tc.header)
+	}
+}
+
+func TestSchemeTLS(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	req.Header.Set(HeaderXForwardedProto, "http")
+	c := newContext(nil, req)
+	assert.Equal(t, "https", c.Scheme())
+	assert.Equal(t, "192.0.2.1", c.ClientIP())
+}
@@ -0,0 +1,213 @@
+package web
+
+import (
+	lrucache "github.com/ywang2728/sampan/ds/lru"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	RateLimitStoreCapacity   = 10000
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+	rateLimitKeySeparator    = "|"
+	rateLimitAnonymousValue  = "-"
+)
+
+type (
+	// RateLimitState is the plain state of one key, so that it can be serialized by a shared store.
+	RateLimitState struct {
+		// Tokens left in the bucket and time of the last refill, for TokenBucket.
+		Tokens float64
+		Last   time.Time
+		// Counters of the current and previous windows and start of the current window, for SlidingWindow.
+		Count     int
+		PrevCount int
+		Window    time.Time
+	}
+
+	RateLimitStore interface {
+		// Update loads the state of the key, or a zero state if it is unknown, passes it to fn and saves it, atomically.
+		Update(key string, fn func(state *RateLimitState))
+	}
+
+	RateLimitResult struct {
+		Allowed    bool
+		Limit      int
+		Remaining  int
+		Reset      time.Duration
+		RetryAfter time.Duration
+	}
+
+	RateLimiter interface {
+		Allow(store RateLimitStore, key string, now time.Time) RateLimitResult
+	}
+
+	// TokenBucket refills Rate tokens per second up to Burst, each request consumes one token.
+	TokenBucket struct {
+		Rate  float64
+		Burst int
+	}
+
+	// SlidingWindow allows Limit requests per Window, the previous window is weighted by its overlap with the sliding one.
+	SlidingWindow struct {
+		Limit  int
+		Window time.Duration
+	}
+
+	// MemoryStore keeps the states in a LRU cache, the least recently seen keys are evicted once the capacity is reached.
+	MemoryStore struct {
+		cache *lrucache.Cache[string, *RateLimitState]
+		mutex sync.Mutex
+	}
+
+	RateLimitConfig struct {
+		Limiter RateLimiter
+		// Store of the states, a MemoryStore of RateLimitStoreCapacity keys by default.
+		Store RateLimitStore
+		// KeyFunc extracts the key to throttle on, RateLimitByIP by default.
+		KeyFunc func(*Context) string
+		// Skip the limiter for the request if it returns true.
+		Skipper func(*Context) bool
+	}
+)
+
+func NewTokenBucket(limit int, per time.Duration, burst int) *TokenBucket {
+	if limit <= 0 || per <= 0 {
+		panic("Token bucket rate should be positive!")
+	}
+	if burst <= 0 {
+		panic("Token bucket burst should be positive!")
+	}
+	return &TokenBucket{Rate: float64(limit) / per.Seconds(), Burst: burst}
+}
+
+func (tb *TokenBucket) Allow(store RateLimitStore, key string, now time.Time) (r RateLimitResult) {
+	r.Limit = tb.Burst
+	store.Update(key, func(state *RateLimitState) {
+		if state.Last.IsZero() {
+			state.Tokens = float64(tb.Burst)
+		} else if elapsed := now.Sub(state.Last).Seconds(); elapsed > 0 {
+			state.Tokens = math.Min(float64(tb.Burst), state.Tokens+elapsed*tb.Rate)
+		}
+		state.Last = now
+		if state.Tokens >= 1 {
+			state.Tokens--
+			r.Allowed = true
+		} else {
+			r.RetryAfter = time.Duration((1 - state.Tokens) / tb.Rate * float64(time.Second))
+		}
+		r.Remaining = int(state.Tokens)
+		r.Reset = time.Duration((float64(tb.Burst) - state.Tokens) / tb.Rate * float64(time.Second))
+	})
+	return
+}
+
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	if limit <= 0 || window <= 0 {
+		panic("Sliding window limit and window should be positive!")
+	}
+	return &SlidingWindow{Limit: limit, Window: window}
+}
+
+func (sw *SlidingWindow) Allow(store RateLimitStore, key string, now time.Time) (r RateLimitResult) {
+	r.Limit = sw.Limit
+	store.Update(key, func(state *RateLimitState) {
+		window := now.Truncate(sw.Window)
+		switch {
+		case state.Window.Equal(window):
+		case state.Window.Add(sw.Window).Equal(window):
+			state.PrevCount, state.Count, state.Window = state.Count, 0, window
+		default:
+			state.PrevCount, state.Count, state.Window = 0, 0, window
+		}
+		weight := 1 - float64(now.Sub(window))/float64(sw.Window)
+		estimated := float64(state.PrevCount)*weight + float64(state.Count)
+		r.Reset = window.Add(sw.Window).Sub(now)
+		if estimated+1 <= float64(sw.Limit) {
+			state.Count++
+			estimated++
+			r.Allowed = true
+		} else {
+			r.RetryAfter = r.Reset
+		}
+		r.Remaining = max(0, sw.Limit-int(math.Ceil(estimated)))
+	})
+	return
+}
+
+func NewMemoryStore(capacity int) *MemoryStore {
+	return &MemoryStore{cache: lrucache.New[string, *RateLimitState](capacity)}
+}
+
+func (s *MemoryStore) Update(key string, fn func(state *RateLimitState)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	state, ok := s.cache.Get(key)
+	if !ok {
+		state = &RateLimitState{}
+	}
+	fn(state)
+	s.cache.Put(key, state)
+}
+
+// RateLimitByIP throttles on the client address, as resolved by Context.ClientIP from the trusted proxies.
+func RateLimitByIP(c *Context) string {
+	return c.ClientIP()
+}
+
+// RateLimitByHeader throttles on an API key carried by the header, requests without it share the same anonymous key.
+func RateLimitByHeader(header string) func(*Context) string {
+	return func(c *Context) string {
+		if v := c.Req.Header.Get(header); v != "" {
+			return v
+		}
+		return rateLimitAnonymousValue
+	}
+}
+
+// RateLimitByRoute throttles every route pattern separately for the key.
+func RateLimitByRoute(keyFunc func(*Context) string) func(*Context) string {
+	return func(c *Context) string {
+		return c.Method + rateLimitKeySeparator + c.Pattern() + rateLimitKeySeparator + keyFunc(c)
+	}
+}
+
+func RateLimit(limiter RateLimiter) func(*Context) {
+	return RateLimitWithConfig(RateLimitConfig{Limiter: limiter})
+}
+
+func RateLimitWithConfig(cfg RateLimitConfig) func(*Context) {
+	if cfg.Limiter == nil {
+		panic("Rate limiter should not be nil!")
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore(RateLimitStoreCapacity)
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = RateLimitByIP
+	}
+	return func(c *Context) {
+		if cfg.Skipper != nil && cfg.Skipper(c) {
+			return
+		}
+		r := cfg.Limiter.Allow(cfg.Store, cfg.KeyFunc(c), time.Now())
+		h := c.Writer.Header()
+		h.Set(HeaderRateLimitLimit, strconv.Itoa(r.Limit))
+		h.Set(HeaderRateLimitRemaining, strconv.Itoa(r.Remaining))
+		h.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(r.Reset)))
+		if !r.Allowed {
+			h.Set(HeaderRetryAfter, strconv.Itoa(ceilSeconds(r.RetryAfter)))
+			c.Error(NewHTTPError(http.StatusTooManyRequests))
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,98 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	store := NewMemoryStore(10)
+	tb := NewTokenBucket(1, time.Second, 2)
+	now := time.Now()
+	assert.True(t, tb.Allow(store, "a", now).Allowed)
+	r := tb.Allow(store, "a", now)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+	r = tb.Allow(store, "a", now)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, time.Second, r.RetryAfter)
+	assert.True(t, tb.Allow(store, "b", now).Allowed)
+	assert.True(t, tb.Allow(store, "a", now.Add(time.Second)).Allowed)
+}
+
+func TestNewLimiterPanics(t *testing.T) {
+	tcs := []struct {
+		name string
+		new  func()
+	}{
+		{name: "zero rate", new: func() { NewTokenBucket(0, time.Second, 1) }},
+		{name: "negative rate", new: func() { NewTokenBucket(-1, time.Second, 1) }},
+		{name: "zero period", new: func() { NewTokenBucket(1, 0, 1) }},
+		{name: "zero burst", new: func() { NewTokenBucket(1, time.Second, 0) }},
+		{name: "zero limit", new: func() { NewSlidingWindow(0, time.Minute) }},
+		{name: "zero window", new: func() { NewSlidingWindow(1, 0) }},
+	}
+	for _, tc := range tcs {
+		assert.Panics(t, tc.new, tc.name)
+	}
+	assert.NotPanics(t, func() { NewTokenBucket(1, time.Second, 1) })
+}
+
+func TestSlidingWindow(t *testing.T) {
+	store := NewMemoryStore(10)
+	sw := NewSlidingWindow(2, time.Minute)
+	now := time.Now().Truncate(time.Minute)
+	assert.True(t, sw.Allow(store, "a", now).Allowed)
+	assert.True(t, sw.Allow(store, "a", now.Add(time.Second)).Allowed)
+	r := sw.Allow(store, "a", now.Add(2*time.Second))
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 58*time.Second, r.RetryAfter)
+	// half of the previous window still counts.
+	assert.True(t, sw.Allow(store, "a", now.Add(90*time.Second)).Allowed)
+	assert.False(t, sw.Allow(store, "a", now.Add(91*time.Second)).Allowed)
+	assert.True(t, sw.Allow(store, "a", now.Add(10*time.Minute)).Allowed)
+}
+
+func TestMemoryStoreBounded(t *testing.T) {
+	store := NewMemoryStore(2)
+	for _, key := range []string{"a", "b", "c"} {
+		store.Update(key, func(state *RateLimitState) { state.Count++ })
+	}
+	assert.Equal(t, 2, store.cache.Len())
+}
+
+func TestRateLimit(t *testing.T) {
+	s := New()
+	g := s.Group("/api")
+	g.PreMiddlewares(RateLimitWithConfig(RateLimitConfig{
+		Limiter: NewTokenBucket(1, time.Hour, 1),
+		KeyFunc: RateLimitByRoute(RateLimitByHeader("X-API-Key")),
+	}))
+	g.GET("/a", func(c *Context) { c.String(http.StatusOK, "a") })
+	g.GET("/b", func(c *Context) { c.String(http.StatusOK, "b") })
+	tcs := []struct {
+		path   string
+		apiKey string
+		code   int
+	}{
+		{path: "/api/a", apiKey: "k1", code: http.StatusOK},
+		{path: "/api/a", apiKey: "k1", code: http.StatusTooManyRequests},
+		{path: "/api/b", apiKey: "k1", code: http.StatusOK},
+		{path: "/api/a", apiKey: "k2", code: http.StatusOK},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("X-API-Key", tc.apiKey)
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code)
+		assert.Equal(t, "1", w.Header().Get(HeaderRateLimitLimit))
+		assert.Equal(t, "0", w.Header().Get(HeaderRateLimitRemaining))
+		if tc.code == http.StatusTooManyRequests {
+			assert.Equal(t, "3600", w.Header().Get(HeaderRetryAfter))
+		}
+	}
+}
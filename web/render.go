@@ -0,0 +1,239 @@
+package web
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"iter"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	MIMEApplicationJSON = "application/json"
+	MIMEApplicationXML  = "application/xml"
+	MIMEApplicationYAML = "application/yaml"
+	MIMEApplicationJS   = "application/javascript"
+	MIMETextCSV         = "text/csv"
+	MIMETextPlain       = "text/plain"
+	MIMETextHTML        = "text/html"
+	MIMEOctetStream     = "application/octet-stream"
+	// StreamJSONFlushEvery is the number of elements written between two flushes of StreamJSON.
+	StreamJSONFlushEvery = 100
+)
+
+var (
+	// SecureJSONPrefix is prepended by SecureJSON, so that the response can not be executed by a <script> tag.
+	SecureJSONPrefix = "while(1);"
+	// NegotiateOffers are the formats of Negotiate, by order of preference when the client accepts several ones.
+	NegotiateOffers = []string{MIMEApplicationJSON, MIMEApplicationXML, MIMEApplicationYAML, MIMETextCSV, MIMETextPlain}
+
+	jsonpCallbackRe = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$.]*$`)
+)
+
+func (c *Context) write(code int, contentType string, body []byte) {
+	c.SetHeader(HeaderContentType, contentType)
+	c.Status(code)
+	_, _ = c.Writer.Write(body)
+}
+
+func (c *Context) IndentedJSON(code int, value any) {
+	b, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		c.Error(NewHTTPError(http.StatusInternalServerError).WithError(err))
+		return
+	}
+	c.write(code, MIMEApplicationJSON, b)
+}
+
+func (c *Context) SecureJSON(code int, value any) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		c.Error(NewHTTPError(http.StatusInternalServerError).WithError(err))
+		return
+	}
+	c.write(code, MIMEApplicationJSON, append([]byte(SecureJSONPrefix), b...))
+}
+
+// JSONP wraps the JSON in a call of the callback, which must be a valid JavaScript identifier path.
+func (c *Context) JSONP(code int, callback string, value any) {
+	if !jsonpCallbackRe.MatchString(callback) {
+		c.Error(NewHTTPError(http.StatusBadRequest, "invalid JSONP callback"))
+		return
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		c.Error(NewHTTPError(http.StatusInternalServerError).WithError(err))
+		return
+	}
+	c.write(code, MIMEApplicationJS+"; charset=utf-8", []byte("/**/"+callback+"("+string(b)+");"))
+}
+
+func (c *Context) XML(code int, value any) {
+	b, err := xml.Marshal(value)
+	if err != nil {
+		c.Error(NewHTTPError(http.StatusInternalServerError).WithError(err))
+		return
+	}
+	c.write(code, MIMEApplicationXML+"; charset=utf-8", append([]byte(xml.Header), b...))
+}
+
+func (c *Context) YAML(code int, value any) {
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		c.Error(NewHTTPError(http.StatusInternalServerError).WithError(err))
+		return
+	}
+	c.write(code, MIMEApplicationYAML+"; charset=utf-8", b)
+}
+
+// CSV writes the records, being a [][]string or a slice of structs whose exported fields are the columns.
+func (c *Context) CSV(code int, value any) {
+	records, ok := csvRecords(value)
+	if !ok {
+		c.Error(NewHTTPError(http.StatusInternalServerError).WithError(fmt.Errorf("%T can not be written as CSV", value)))
+		return
+	}
+	c.SetHeader(HeaderContentType, MIMETextCSV+"; charset=utf-8")
+	c.Status(code)
+	w := csv.NewWriter(c.Writer)
+	_ = w.WriteAll(records)
+}
+
+// csvRecords converts the value to records, the header of a struct slice is the "csv" tag or the name of the fields.
+func csvRecords(value any) (records [][]string, ok bool) {
+	if records, ok = value.([][]string); ok {
+		return
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice {
+		return nil, false
+	}
+	t := v.Type().Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	var fields []int
+	var header []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, _, _ := strings.Cut(f.Tag.Get("csv"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields = append(fields, i)
+		header = append(header, name)
+	}
+	records = append(records, header)
+	for i := 0; i < v.Len(); i++ {
+		e := v.Index(i)
+		for e.Kind() == reflect.Pointer {
+			e = e.Elem()
+		}
+		record := make([]string, len(fields))
+		if e.IsValid() {
+			for j, f := range fields {
+				record[j] = fmt.Sprint(e.Field(f).Interface())
+			}
+		}
+		records = append(records, record)
+	}
+	return records, true
+}
+
+// matchMediaRange reports if the media range of the Accept header, like "text/*", covers the media type.
+func matchMediaRange(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// NegotiateFormat returns the first offer accepted with the highest quality, the first offer if there is no Accept header.
+func (c *Context) NegotiateFormat(offers ...string) string {
+	accept := c.Req.Header.Get("Accept")
+	if accept == "" && len(offers) > 0 {
+		return offers[0]
+	}
+	for _, av := range parseAcceptHeader(accept) {
+		if av.q <= 0 {
+			continue
+		}
+		for _, offer := range offers {
+			if matchMediaRange(av.value, offer) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// Negotiate writes the value in the format preferred by the Accept header among NegotiateOffers, 406 if none is accepted.
+func (c *Context) Negotiate(code int, value any) {
+	offers := NegotiateOffers
+	if _, ok := csvRecords(value); !ok {
+		offers = make([]string, 0, len(NegotiateOffers))
+		for _, offer := range NegotiateOffers {
+			if offer != MIMETextCSV {
+				offers = append(offers, offer)
+			}
+		}
+	}
+	c.Writer.Header().Add(HeaderVary, "Accept")
+	switch c.NegotiateFormat(offers...) {
+	case MIMEApplicationJSON:
+		c.JSON(code, value)
+	case MIMEApplicationXML:
+		c.XML(code, value)
+	case MIMEApplicationYAML:
+		c.YAML(code, value)
+	case MIMETextCSV:
+		c.CSV(code, value)
+	case MIMETextPlain:
+		c.String(code, "%v", value)
+	default:
+		c.Error(NewHTTPError(http.StatusNotAcceptable))
+	}
+}
+
+// StreamJSON writes the elements of seq as a JSON array without holding them in memory, flushing regularly.
+// An error of seq stops the stream, the truncated array tells the client that the response is incomplete.
+func StreamJSON[T any](c *Context, code int, seq iter.Seq2[T, error]) {
+	c.SetHeader(HeaderContentType, MIMEApplicationJSON)
+	c.Status(code)
+	flusher, _ := c.Writer.(http.Flusher)
+	encoder := json.NewEncoder(c.Writer)
+	if _, err := c.Writer.Write([]byte("[")); err != nil {
+		return
+	}
+	n := 0
+	for e, err := range seq {
+		if err == nil && n > 0 {
+			_, err = c.Writer.Write([]byte(","))
+		}
+		if err == nil {
+			err = encoder.Encode(e)
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if n++; flusher != nil && n%StreamJSONFlushEvery == 0 {
+			flusher.Flush()
+		}
+	}
+	_, _ = c.Writer.Write([]byte("]"))
+}
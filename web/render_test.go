@@ -0,0 +1,101 @@
+package web
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type renderItem struct {
+	ID     int    `json:"id" xml:"id" yaml:"id" csv:"id"`
+	Name   string `json:"name" xml:"name" yaml:"name" csv:"name"`
+	secret string
+}
+
+func TestNegotiate(t *testing.T) {
+	items := []renderItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	tcs := []struct {
+		accept      string
+		value       any
+		code        int
+		contentType string
+		body        string
+	}{
+		{accept: "", value: items, code: http.StatusOK, contentType: MIMEApplicationJSON, body: `[{"id":1,"name":"a"},{"id":2,"name":"b"}]` + "\n"},
+		{accept: "text/csv;q=0.9, application/yaml;q=0.5", value: items, code: http.StatusOK, contentType: MIMETextCSV + "; charset=utf-8", body: "id,name\n1,a\n2,b\n"},
+		{accept: "application/yaml", value: items, code: http.StatusOK, contentType: MIMEApplicationYAML + "; charset=utf-8", body: "- id: 1\n  name: a\n- id: 2\n  name: b\n"},
+		{accept: "application/xml", value: renderItem{ID: 1, Name: "a"}, code: http.StatusOK, contentType: MIMEApplicationXML + "; charset=utf-8", body: `<?xml version="1.0" encoding="UTF-8"?>` + "\n<renderItem><id>1</id><name>a</name></renderItem>"},
+		{accept: "text/*", value: "hello", code: http.StatusOK, contentType: MIMETextPlain, body: "hello"},
+		{accept: "text/csv", value: "hello", code: http.StatusNotAcceptable, contentType: MIMETextPlain, body: "Not Acceptable\n"},
+		{accept: "image/png", value: items, code: http.StatusNotAcceptable, contentType: MIMETextPlain, body: "Not Acceptable\n"},
+	}
+	for _, tc := range tcs {
+		s := New()
+		s.GET("/", func(c *Context) { c.Negotiate(http.StatusOK, tc.value) })
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", tc.accept)
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, tc.accept)
+		assert.Equal(t, tc.contentType, w.Header().Get(HeaderContentType), tc.accept)
+		assert.Equal(t, tc.body, w.Body.String(), tc.accept)
+	}
+}
+
+func TestJSONVariants(t *testing.T) {
+	value := map[string]int{"a": 1}
+	tcs := []struct {
+		render func(c *Context)
+		code   int
+		body   string
+	}{
+		{render: func(c *Context) { c.IndentedJSON(http.StatusOK, value) }, code: http.StatusOK, body: "{\n    \"a\": 1\n}"},
+		{render: func(c *Context) { c.SecureJSON(http.StatusOK, value) }, code: http.StatusOK, body: `while(1);{"a":1}`},
+		{render: func(c *Context) { c.JSONP(http.StatusOK, "cb.done", value) }, code: http.StatusOK, body: `/**/cb.done({"a":1});`},
+		{render: func(c *Context) { c.JSONP(http.StatusOK, "alert(1)", value) }, code: http.StatusBadRequest, body: "invalid JSONP callback\n"},
+	}
+	for _, tc := range tcs {
+		s := New()
+		s.GET("/", tc.render)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, tc.code, w.Code)
+		assert.Equal(t, tc.body, w.Body.String())
+	}
+}
+
+func TestStreamJSON(t *testing.T) {
+	seq := func(n int, err error) iter.Seq2[int, error] {
+		return func(yield func(int, error) bool) {
+			for i := 0; i < n; i++ {
+				if !yield(i, nil) {
+					return
+				}
+			}
+			if err != nil {
+				yield(0, err)
+			}
+		}
+	}
+	s := New()
+	s.GET("/ok", func(c *Context) { StreamJSON(c, http.StatusOK, seq(3, nil)) })
+	s.GET("/empty", func(c *Context) { StreamJSON(c, http.StatusOK, seq(0, nil)) })
+	s.GET("/broken", func(c *Context) { StreamJSON(c, http.StatusOK, seq(2, errors.New("db"))) })
+	tcs := []struct {
+		path string
+		body string
+	}{
+		{path: "/ok", body: "[0\n,1\n,2\n]"},
+		{path: "/empty", body: "[]"},
+		{path: "/broken", body: "[0\n,1\n"},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, tc.body, w.Body.String())
+	}
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"context"
+	"github.com/google/uuid"
+)
+
+const (
+	HeaderXRequestID   = "X-Request-ID"
+	RequestIDMaxLength = 128
+)
+
+type (
+	RequestIDConfig struct {
+		// Header to read the incoming ID from and to echo it in, X-Request-ID by default.
+		Header string
+		// Generator creates the ID when the request does not carry a valid one, UUID v4 by default.
+		Generator func() string
+	}
+
+	requestIDKey struct{}
+)
+
+func RequestID() func(*Context) {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+func RequestIDWithConfig(cfg RequestIDConfig) func(*Context) {
+	if cfg.Header == "" {
+		cfg.Header = HeaderXRequestID
+	}
+	if cfg.Generator == nil {
+		cfg.Generator = uuid.NewString
+	}
+	return func(c *Context) {
+		id := c.Req.Header.Get(cfg.Header)
+		if !isValidRequestID(id) {
+			id = cfg.Generator()
+		}
+		c.requestID = id
+		c.logger = nil
+		c.Req = c.Req.WithContext(context.WithValue(c.Req.Context(), requestIDKey{}, id))
+		c.SetHeader(cfg.Header, id)
+	}
+}
+
+// The incoming ID ends up in the logs, only short printable ASCII values are accepted.
+func isValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > RequestIDMaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func (c *Context) RequestID() string {
+	return c.requestID
+}
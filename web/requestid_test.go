@@ -0,0 +1,70 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestID(t *testing.T) {
+	tcs := []struct {
+		incoming string
+		expected string
+	}{
+		{incoming: "abc-123", expected: "abc-123"},
+		{incoming: "", expected: "generated"},
+		{incoming: "bad id", expected: "generated"},
+		{incoming: strings.Repeat("a", RequestIDMaxLength+1), expected: "generated"},
+	}
+	s := New()
+	s.PreMiddlewares(RequestIDWithConfig(RequestIDConfig{Generator: func() string { return "generated" }}))
+	s.GET("/users/{(?P<id>\\d+)}", func(c *Context) {
+		assert.Equal(t, c.RequestID(), RequestIDFromContext(c.Req.Context()))
+		assert.Equal(t, "/users/{(?P<id>\\d+)}", c.Pattern())
+		assert.NotNil(t, c.Logger())
+		c.String(http.StatusOK, c.RequestID())
+	})
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set(HeaderXRequestID, tc.incoming)
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.expected, w.Header().Get(HeaderXRequestID))
+		assert.Equal(t, tc.expected, w.Body.String())
+	}
+}
+
+func TestRequestIDDefaultGenerator(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	RequestID()(c)
+	assert.Len(t, c.RequestID(), 36)
+	assert.Equal(t, c.RequestID(), c.Writer.Header().Get(HeaderXRequestID))
+}
+
+func TestRequestIDForked(t *testing.T) {
+	wrappers := map[string]func(*Context){
+		"timeout": Timeout(time.Second),
+		"wrapped": WrapMiddleware(func(next http.Handler) http.Handler { return next }),
+	}
+	for name, wrapper := range wrappers {
+		var id, ctxID string
+		s := New()
+		s.ErrorHandler = func(c *Context, err error) {
+			id, ctxID = c.RequestID(), RequestIDFromContext(c.Req.Context())
+			DefaultErrorHandler(c, err)
+		}
+		s.PreMiddlewares(wrapper, RequestID())
+		s.GET("/", func(c *Context) { c.Error(NewHTTPError(http.StatusConflict)) })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderXRequestID, "abc")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code, name)
+		assert.Equal(t, "abc", w.Header().Get(HeaderXRequestID), name)
+		assert.Equal(t, "abc", id, name)
+		assert.Equal(t, "abc", ctxID, name)
+	}
+}
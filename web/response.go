@@ -0,0 +1,118 @@
+package web
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+type (
+	// responseWriter records the status and size of the response, so that the server knows if it has been committed.
+	responseWriter struct {
+		http.ResponseWriter
+		status  int
+		size    int
+		written bool
+	}
+
+	// discardWriter is the writer of the copies of the Context.
+	discardWriter struct {
+		header http.Header
+	}
+
+	// bufferedWriter holds the response back before committing it to the writer it wraps, what it holds is discarded
+	// when an error is rendered instead.
+	bufferedWriter interface {
+		http.ResponseWriter
+		discard()
+		// heldStatus is the status not committed yet, zero if there is none.
+		heldStatus() int
+	}
+)
+
+func (w discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w discardWriter) WriteHeader(int) {}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.written {
+		return
+	}
+	w.status = code
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (n int, err error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err = w.ResponseWriter.Write(b)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) Flush() {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.written = true
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("the response writer does not implement http.Hijacker")
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// discardResponse drops what the writers of the Context hold back, so that an error response does not follow a partial body.
+func (c *Context) discardResponse() {
+	for w := c.Writer; w != nil; {
+		if bw, ok := w.(bufferedWriter); ok {
+			bw.discard()
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = u.Unwrap()
+	}
+}
+
+// responseStatus is the status the response is committed with, or held back with by the writers of the Context,
+// 200 if there is none yet.
+func (c *Context) responseStatus() int {
+	for w := c.Writer; w != nil; {
+		switch rw := w.(type) {
+		case *responseWriter:
+			if rw.written {
+				return rw.status
+			}
+		case bufferedWriter:
+			if status := rw.heldStatus(); status != 0 {
+				return status
+			}
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return http.StatusOK
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"path"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -33,6 +34,8 @@ type (
 		part       string
 		rePatterns []*rePattern
 		handler    func(*Context)
+		//route ending on this node.
+		route *Route
 		//store non-regex nodes by first segment of tail of path as map key.
 		children map[string]*node
 		//store regex nodes, keep the insert order for seeking.
@@ -46,10 +49,12 @@ type (
 	}
 
 	lru struct {
-		nodes *list.List
-		paths map[string]*list.Element
-		cap   int
-		mutex sync.Mutex
+		nodes  *list.List
+		paths  map[string]*list.Element
+		cap    int
+		mutex  sync.Mutex
+		hits   atomic.Uint64
+		misses atomic.Uint64
 	}
 
 	radix struct {
@@ -61,6 +66,10 @@ type (
 
 	router struct {
 		trees map[string]*radix
+		// mutex guards the trees map, every tree guarding its own nodes.
+		mutex sync.RWMutex
+		// generation is increased by the changes of the groups and the handlers, invalidating the handler chains.
+		generation atomic.Uint64
 	}
 
 	RouterGroup struct {
@@ -70,6 +79,26 @@ type (
 		router          *router
 		parent          *RouterGroup
 		children        map[string]*RouterGroup
+		permissions     []Permission
+		maxBodyBytes    int64
+	}
+
+	// Route is a registered handler, with the group it belongs to and its declared metadata.
+	Route struct {
+		Method      string
+		Pattern     string
+		group       *RouterGroup
+		permissions []Permission
+		csrfExempt  bool
+		middlewares []func(*Context)
+		doc         routeDoc
+		chain       atomic.Pointer[handlerChain]
+	}
+
+	// handlerChain of a route, built once for the router generation.
+	handlerChain struct {
+		generation uint64
+		handlers   []func(*Context)
 	}
 )
 
@@ -85,6 +114,7 @@ func (l *lru) clear() {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 	l.nodes.Init()
+	clear(l.paths)
 }
 func (l *lru) len() int {
 	return l.nodes.Len()
@@ -108,6 +138,9 @@ func (l *lru) get(path string) (n *node, params map[string]string) {
 		l.nodes.MoveToFront(e)
 		n = e.Value.(*lruNode).node
 		params = e.Value.(*lruNode).params
+		l.hits.Add(1)
+	} else {
+		l.misses.Add(1)
 	}
 	return
 }
@@ -368,17 +401,40 @@ func (r *radix) putRec(n *node, path string, handler func(ctx *Context)) (t *nod
 	return
 }
 
-func (r *radix) put(path string, handler func(*Context)) (b bool) {
+func (r *radix) put(path string, handler func(*Context), route *Route) (b bool) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	if t := r.putRec(r.root, path, handler); t != nil {
 		r.root = t
 		r.size++
+		if n := r.findRec(t, path); n != nil {
+			n.route = route
+		}
 		b = true
 	}
 	return
 }
 
+// Find the node by the raw path, regex segments are compared as plain text.
+func (r *radix) findRec(n *node, path string) (t *node) {
+	if after, ok := strings.CutPrefix(path, n.part); ok {
+		if len(after) > 0 {
+			if child, ok := n.children[parseKey(after)]; ok {
+				t = r.findRec(child, after)
+			} else {
+				for _, reChild := range n.reChildren {
+					if t = r.findRec(reChild, after); t != nil {
+						break
+					}
+				}
+			}
+		} else if n.handler != nil {
+			t = n
+		}
+	}
+	return
+}
+
 func (r *radix) getRec(n *node, path string, params map[string]string) (t *node) {
 	isMatched := true
 	before := strings.Builder{}
@@ -432,8 +488,8 @@ func (r *radix) getRec(n *node, path string, params map[string]string) (t *node)
 	return
 }
 
-// Get handler from cache by path, if it's not exist, get recursively from tree.
-func (r *radix) get(path string) (func(*Context), map[string]string) {
+// Get node from cache by path, if it's not exist, get recursively from tree.
+func (r *radix) get(path string) (*node, map[string]string) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 	n, params := r.cache.get(path)
@@ -441,14 +497,14 @@ func (r *radix) get(path string) (func(*Context), map[string]string) {
 		if r.root == nil {
 			return nil, nil
 		}
-		params := make(map[string]string)
+		params = make(map[string]string)
 		if n = r.getRec(r.root, path, params); n != nil {
 			r.cache.put(path, n, params)
 		} else {
 			return nil, nil
 		}
 	}
-	return n.handler, params
+	return n, params
 }
 
 // Delete leaf node, then recursively delete parent node if it's alone
@@ -484,6 +540,7 @@ func (r *radix) deleteRec(n *node, path string) (b bool) {
 			}
 		} else {
 			n.handler = nil
+			n.route = nil
 			b = true
 		}
 	}
@@ -537,6 +594,28 @@ func (r *radix) update(path string, handler func(*Context)) (b bool) {
 	return r.updateRec(r.root, path, handler)
 }
 
+func (r *radix) routesRec(n *node, routes []*Route) []*Route {
+	if n.handler != nil && n.route != nil {
+		routes = append(routes, n.route)
+	}
+	for _, child := range n.children {
+		routes = r.routesRec(child, routes)
+	}
+	for _, reChild := range n.reChildren {
+		routes = r.routesRec(reChild, routes)
+	}
+	return routes
+}
+
+func (r *radix) routes() (routes []*Route) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if r.root != nil {
+		routes = r.routesRec(r.root, routes)
+	}
+	return
+}
+
 func newRouter() *router {
 	return &router{
 		trees: make(map[string]*radix),
@@ -544,19 +623,30 @@ func newRouter() *router {
 }
 
 func (r *router) clear() {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 	for _, r := range r.trees {
 		r.clear()
 	}
 }
 
 func (r *router) len() (l int) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 	for _, r := range r.trees {
 		l += r.len()
 	}
 	return
 }
 
-func (r *router) put(method string, path string, handler func(*Context)) {
+func (r *router) tree(method string) (tree *radix, ok bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	tree, ok = r.trees[method]
+	return
+}
+
+func (r *router) put(method string, path string, handler func(*Context)) (route *Route) {
 	log.Printf("Put route %4s - %s", method, path)
 	if !strings.HasPrefix(path, "/") {
 		panic("Path must begin with '/'!")
@@ -564,19 +654,24 @@ func (r *router) put(method string, path string, handler func(*Context)) {
 	if handler == nil {
 		panic("Handler function should not be nil!")
 	}
-	if _, ok := r.trees[method]; !ok {
-		r.trees[method] = newRadix()
-	}
-	r.trees[method].put(path, handler)
+	r.mutex.Lock()
+	tree, ok := r.trees[method]
+	if !ok {
+		tree = newRadix()
+		r.trees[method] = tree
+	}
+	r.mutex.Unlock()
+	route = &Route{Method: method, Pattern: path}
+	tree.put(path, handler, route)
+	return
 }
 
-func (r *router) get(method string, path string) (handler func(*Context), params map[string]string) {
-	log.Printf("Get route %4s - %s", method, path)
+func (r *router) get(method string, path string) (n *node, params map[string]string) {
 	if path[0] != '/' {
 		panic("Path must begin with '/'!")
 	}
-	if tree, ok := r.trees[method]; ok {
-		handler, params = tree.get(path)
+	if tree, ok := r.tree(method); ok {
+		n, params = tree.get(path)
 	}
 	return
 }
@@ -586,7 +681,7 @@ func (r *router) delete(method string, path string) (b bool) {
 	if path[0] != '/' {
 		panic("Path must begin with '/'!")
 	}
-	if tree, ok := r.trees[method]; ok {
+	if tree, ok := r.tree(method); ok {
 		b = tree.delete(path)
 	}
 	return
@@ -597,9 +692,38 @@ func (r *router) update(method string, path string, handler func(*Context)) (b b
 	if path[0] != '/' {
 		panic("Path must begin with '/'!")
 	}
-	if tree, ok := r.trees[method]; ok {
-		b = tree.update(path, handler)
+	if tree, ok := r.tree(method); ok {
+		if b = tree.update(path, handler); b {
+			r.generation.Add(1)
+		}
+	}
+	return
+}
+
+// cacheStats sums the hits and misses of the path caches of all the methods.
+func (r *router) cacheStats() (hits uint64, misses uint64) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, tree := range r.trees {
+		hits += tree.cache.hits.Load()
+		misses += tree.cache.misses.Load()
+	}
+	return
+}
+
+// routes returns all the registered routes sorted by pattern then method.
+func (r *router) routes() (routes []*Route) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, tree := range r.trees {
+		routes = append(routes, tree.routes()...)
 	}
+	slices.SortFunc(routes, func(a, b *Route) int {
+		if c := strings.Compare(a.Pattern, b.Pattern); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
 	return
 }
 
@@ -652,6 +776,7 @@ func (rg *RouterGroup) DeleteGroup(prefix string) *RouterGroup {
 
 func (rg *RouterGroup) PreMiddlewares(middlewares ...func(*Context)) *RouterGroup {
 	rg.preMiddlewares = append(rg.preMiddlewares, middlewares...)
+	rg.router.generation.Add(1)
 	return rg
 }
 
@@ -665,6 +790,7 @@ func (rg *RouterGroup) getPreMiddlewares() (preMiddlewares []func(*Context)) {
 
 func (rg *RouterGroup) PostMiddlewares(middlewares ...func(*Context)) *RouterGroup {
 	rg.postMiddlewares = append(rg.postMiddlewares, middlewares...)
+	rg.router.generation.Add(1)
 	return rg
 }
 
@@ -676,55 +802,87 @@ func (rg *RouterGroup) getPostMiddlewares() (postMiddlewares []func(*Context)) {
 	return
 }
 
-func (rg *RouterGroup) PutRoute(method string, path string, handler func(*Context)) {
-	rg.router.put(method, rg.getPrefix()+path, handler)
+func (rg *RouterGroup) PutRoute(method string, path string, handler func(*Context)) (route *Route) {
+	route = rg.router.put(method, rg.getPrefix()+path, handler)
+	route.group = rg
+	return
+}
+
+func (rg *RouterGroup) GET(path string, handler func(*Context)) *Route {
+	return rg.PutRoute(http.MethodGet, path, handler)
 }
 
-func (rg *RouterGroup) GET(path string, handler func(*Context)) {
-	rg.PutRoute(http.MethodGet, path, handler)
+func (rg *RouterGroup) POST(path string, handler func(*Context)) *Route {
+	return rg.PutRoute(http.MethodPost, path, handler)
 }
 
-func (rg *RouterGroup) POST(path string, handler func(*Context)) {
-	rg.PutRoute(http.MethodPost, path, handler)
+func (rg *RouterGroup) PUT(path string, handler func(*Context)) *Route {
+	return rg.PutRoute(http.MethodPut, path, handler)
 }
 
-func (rg *RouterGroup) PUT(path string, handler func(*Context)) {
-	rg.PutRoute(http.MethodPut, path, handler)
+func (rg *RouterGroup) PATCH(path string, handler func(*Context)) *Route {
+	return rg.PutRoute(http.MethodPatch, path, handler)
 }
 
-func (rg *RouterGroup) PATCH(path string, handler func(*Context)) {
-	rg.PutRoute(http.MethodPatch, path, handler)
+func (rg *RouterGroup) DELETE(path string, handler func(*Context)) *Route {
+	return rg.PutRoute(http.MethodDelete, path, handler)
 }
 
-func (rg *RouterGroup) DELETE(path string, handler func(*Context)) {
-	rg.PutRoute(http.MethodDelete, path, handler)
+func (rg *RouterGroup) HEAD(path string, handler func(*Context)) *Route {
+	return rg.PutRoute(http.MethodHead, path, handler)
 }
 
-func (rg *RouterGroup) HEAD(path string, handler func(*Context)) {
-	rg.PutRoute(http.MethodHead, path, handler)
+func (rg *RouterGroup) OPTIONS(path string, handler func(*Context)) *Route {
+	return rg.PutRoute(http.MethodOptions, path, handler)
 }
 
-func (rg *RouterGroup) OPTIONS(path string, handler func(*Context)) {
-	rg.PutRoute(http.MethodOptions, path, handler)
+// Use adds middlewares running for this route only, after the ones of its groups and the authorization.
+func (r *Route) Use(middlewares ...func(*Context)) *Route {
+	r.middlewares = append(r.middlewares, middlewares...)
+	r.chain.Store(nil)
+	return r
 }
 
 func (rg *RouterGroup) GetRoute(method string, path string) (handlerChain []func(*Context), params map[string]string) {
-	var handler func(*Context)
-	g := rg
-	p := path
-	for prefix, child := range g.children {
-		if after, ok := strings.CutPrefix(p, prefix); ok {
-			g = child
-			p = after
+	handlerChain, params, _ = rg.getRoute(method, path)
+	return
+}
+
+// getRoute returns the handler chain built from the group the matched route is registered on, the path parameters and the route.
+func (rg *RouterGroup) getRoute(method string, path string) (handlers []func(*Context), params map[string]string, route *Route) {
+	n, params := rg.router.get(method, path)
+	if n != nil && n.handler != nil {
+		if route = n.route; route != nil && route.group != nil {
+			handlers = route.handlers(n.handler)
+		} else {
+			handlers = rg.buildChain(route, n.handler)
 		}
 	}
-	handler, params = rg.router.get(method, path)
-	if handler != nil {
-		handlerChain = []func(*Context){}
-		handlerChain = append(handlerChain, g.getPreMiddlewares()...)
-		handlerChain = append(handlerChain, handler)
-		handlerChain = append(handlerChain, g.getPostMiddlewares()...)
+	return
+}
+
+// handlers returns the chain precomputed for the current router generation, it is shared by the requests and must not be modified.
+func (r *Route) handlers(handler func(*Context)) []func(*Context) {
+	generation := r.group.router.generation.Load()
+	if chain := r.chain.Load(); chain != nil && chain.generation == generation {
+		return chain.handlers
+	}
+	handlers := slices.Clip(r.group.buildChain(r, handler))
+	r.chain.Store(&handlerChain{generation: generation, handlers: handlers})
+	return handlers
+}
+
+func (rg *RouterGroup) buildChain(route *Route, handler func(*Context)) (handlers []func(*Context)) {
+	handlers = append(handlers, limitBody)
+	handlers = append(handlers, rg.getPreMiddlewares()...)
+	if permissions := route.getPermissions(); len(permissions) > 0 {
+		handlers = append(handlers, authorize(permissions))
+	}
+	if route != nil {
+		handlers = append(handlers, route.middlewares...)
 	}
+	handlers = append(handlers, handler)
+	handlers = append(handlers, rg.getPostMiddlewares()...)
 	return
 }
 
@@ -748,7 +906,7 @@ func (rg *RouterGroup) PutStaticRoute(relativePath string, dir string) {
 
 		fs.ServeHTTP(ctx.Writer, ctx.Req)
 	}
-	rg.router.put(http.MethodGet, path.Join(absolutePath, "/{(?P<filepath>.+)}"), handler)
+	rg.router.put(http.MethodGet, path.Join(absolutePath, "/{(?P<filepath>.+)}"), handler).group = rg
 }
 
 func (rg *RouterGroup) DeleteStaticRoute(relativePath string) {
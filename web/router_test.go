@@ -98,6 +98,18 @@ func TestLruClear(t *testing.T) {
 		l.clear()
 		assert.Equal(t, 0, l.len())
 	}
+	for _, tc := range LruTcs {
+		l := newLru(tc.cap)
+		for _, n := range tc.nodes {
+			l.put(n.path, n.node, nil)
+		}
+		l.clear()
+		assert.Empty(t, l.paths)
+		for _, n := range tc.nodes {
+			got, _ := l.get(n.path)
+			assert.Nil(t, got)
+		}
+	}
 }
 
 func TestLruLen(t *testing.T) {
@@ -583,6 +595,18 @@ func TestRadixGetRec(t *testing.T) {
 	assert.Equal(t, len(tcs), len(cnt))
 }
 
+func TestRadixGetParams(t *testing.T) {
+	r := newRadix()
+	r.put(`/users/{(?P<id>\d+)}`, func(ctx *Context) {}, nil)
+	for i := 0; i < 2; i++ {
+		n, params := r.get("/users/42")
+		assert.NotNil(t, n)
+		assert.Equal(t, map[string]string{"id": "42"}, params)
+	}
+	assert.Equal(t, uint64(1), r.cache.misses.Load())
+	assert.Equal(t, uint64(1), r.cache.hits.Load())
+}
+
 func TestRadixDeleteRec(t *testing.T) {
 	tcs := []struct {
 		path    string
@@ -0,0 +1,96 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	HeaderStrictTransportSecurity = "Strict-Transport-Security"
+	HeaderContentSecurityPolicy   = "Content-Security-Policy"
+	HeaderCSPReportOnly           = "Content-Security-Policy-Report-Only"
+	HeaderXContentTypeOptions     = "X-Content-Type-Options"
+	HeaderXFrameOptions           = "X-Frame-Options"
+	HeaderReferrerPolicy          = "Referrer-Policy"
+	HeaderPermissionsPolicy       = "Permissions-Policy"
+	// CSPNoncePlaceholder in the policy is replaced by the nonce of the request.
+	CSPNoncePlaceholder = "{nonce}"
+	cspNonceLength      = 16
+)
+
+// SecureConfig sets the headers of the non-empty fields, the empty ones remove the header set by a parent group.
+type SecureConfig struct {
+	// HSTSMaxAge in seconds, Strict-Transport-Security is not sent if zero.
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// ContentSecurityPolicy like "script-src 'self' 'nonce-{nonce}'", the nonce is exposed by Context.CSPNonce.
+	ContentSecurityPolicy string
+	// CSPReportOnly sends the policy as Content-Security-Policy-Report-Only, to roll it out without breaking pages.
+	CSPReportOnly      bool
+	ContentTypeNosniff string
+	XFrameOptions      string
+	ReferrerPolicy     string
+	PermissionsPolicy  string
+}
+
+var DefaultSecureConfig = SecureConfig{
+	HSTSMaxAge:            31536000,
+	HSTSIncludeSubdomains: true,
+	ContentSecurityPolicy: "default-src 'self'",
+	ContentTypeNosniff:    "nosniff",
+	XFrameOptions:         "SAMEORIGIN",
+	ReferrerPolicy:        "strict-origin-when-cross-origin",
+}
+
+func (c *Context) CSPNonce() string {
+	return c.cspNonce
+}
+
+func Secure() func(*Context) {
+	return SecureWithConfig(DefaultSecureConfig)
+}
+
+func SecureWithConfig(cfg SecureConfig) func(*Context) {
+	var hsts string
+	if cfg.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", cfg.HSTSMaxAge)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	cspHeader, cspOther := HeaderContentSecurityPolicy, HeaderCSPReportOnly
+	if cfg.CSPReportOnly {
+		cspHeader, cspOther = cspOther, cspHeader
+	}
+	withNonce := strings.Contains(cfg.ContentSecurityPolicy, CSPNoncePlaceholder)
+	return func(c *Context) {
+		h := c.Writer.Header()
+		setOrDel := func(key, value string) {
+			if value == "" {
+				h.Del(key)
+			} else {
+				h.Set(key, value)
+			}
+		}
+		setOrDel(HeaderStrictTransportSecurity, hsts)
+		setOrDel(HeaderXContentTypeOptions, cfg.ContentTypeNosniff)
+		setOrDel(HeaderXFrameOptions, cfg.XFrameOptions)
+		setOrDel(HeaderReferrerPolicy, cfg.ReferrerPolicy)
+		setOrDel(HeaderPermissionsPolicy, cfg.PermissionsPolicy)
+		csp := cfg.ContentSecurityPolicy
+		if withNonce {
+			b := make([]byte, cspNonceLength)
+			_, _ = rand.Read(b)
+			c.cspNonce = base64.StdEncoding.EncodeToString(b)
+			csp = strings.ReplaceAll(csp, CSPNoncePlaceholder, c.cspNonce)
+		}
+		h.Del(cspOther)
+		setOrDel(cspHeader, csp)
+	}
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecure(t *testing.T) {
+	s := New()
+	s.PreMiddlewares(Secure())
+	s.GET("/", func(c *Context) { c.String(http.StatusOK, c.CSPNonce()) })
+	embed := s.Group("/embed").PreMiddlewares(SecureWithConfig(SecureConfig{
+		ContentSecurityPolicy: "script-src 'nonce-" + CSPNoncePlaceholder + "'",
+		CSPReportOnly:         true,
+		ContentTypeNosniff:    "nosniff",
+		PermissionsPolicy:     "camera=()",
+	}))
+	embed.GET("/widget", func(c *Context) { c.String(http.StatusOK, c.CSPNonce()) })
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	h := w.Header()
+	assert.Equal(t, "max-age=31536000; includeSubDomains", h.Get(HeaderStrictTransportSecurity))
+	assert.Equal(t, "default-src 'self'", h.Get(HeaderContentSecurityPolicy))
+	assert.Equal(t, "nosniff", h.Get(HeaderXContentTypeOptions))
+	assert.Equal(t, "SAMEORIGIN", h.Get(HeaderXFrameOptions))
+	assert.Equal(t, "strict-origin-when-cross-origin", h.Get(HeaderReferrerPolicy))
+	assert.Empty(t, w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/embed/widget", nil))
+	h = w.Header()
+	nonce := w.Body.String()
+	assert.NotEmpty(t, nonce)
+	assert.Equal(t, "script-src 'nonce-"+nonce+"'", h.Get(HeaderCSPReportOnly))
+	assert.Empty(t, h.Get(HeaderContentSecurityPolicy))
+	assert.Empty(t, h.Get(HeaderStrictTransportSecurity))
+	assert.Empty(t, h.Get(HeaderXFrameOptions))
+	assert.Equal(t, "camera=()", h.Get(HeaderPermissionsPolicy))
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/embed/widget", nil))
+	assert.NotEqual(t, nonce, w.Body.String())
+}
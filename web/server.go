@@ -1,19 +1,50 @@
 package web
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"net/http"
-	"runtime"
-	"strings"
+	"net/netip"
+	"sync"
+	"time"
 )
 
+const DefaultReadHeaderTimeout = 10 * time.Second
+
 type Server struct {
 	rg *RouterGroup
+	// ErrorHandler renders the errors recorded by Context.Error and the recovered panics.
+	ErrorHandler func(*Context, error)
+	// PanicHandler is called with the recovered value before the panic is turned into a 500 error.
+	PanicHandler func(*Context, any)
+	// Renderer executes the templates of Context.Render.
+	Renderer Renderer
+	// Keyring signs and encrypts the cookies of Context.SetSignedCookie and Context.SetEncryptedCookie.
+	Keyring *Keyring
+	// MaxBodyBytes caps the request bodies, unless a group sets its own limit, no limit if zero.
+	MaxBodyBytes int64
+	// ReadHeaderTimeout of the listening server, DefaultReadHeaderTimeout by default against the slow clients.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout of the listening server to read the whole request, no timeout if zero to allow the long uploads.
+	ReadTimeout    time.Duration
+	trustedProxies []netip.Prefix
+	srv            *http.Server
+	srvMutex       sync.Mutex
+	shutdown       chan struct{}
+	shutdownOnce   sync.Once
+	pool           sync.Pool
 }
 
 func New() (s *Server) {
-	s = &Server{}
+	s = &Server{
+		ErrorHandler:      DefaultErrorHandler,
+		PanicHandler:      DefaultPanicHandler,
+		ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		shutdown:          make(chan struct{}),
+	}
+	s.pool.New = func() any {
+		return new(Context)
+	}
 	s.rg = NewRouterGroup("", newRouter())
 	return
 }
@@ -38,67 +69,120 @@ func (s *Server) GetRoute(method string, path string) (handlerChain []func(*Cont
 	return s.rg.GetRoute(method, path)
 }
 
-func (s *Server) PutRoute(method string, path string, handler func(*Context)) {
-	s.rg.PutRoute(method, path, handler)
+func (s *Server) Routes() []*Route {
+	return s.rg.router.routes()
+}
+
+func (s *Server) PutRoute(method string, path string, handler func(*Context)) *Route {
+	return s.rg.PutRoute(method, path, handler)
 }
 
-func (s *Server) GET(path string, handler func(*Context)) {
-	s.PutRoute(http.MethodGet, path, handler)
+func (s *Server) GET(path string, handler func(*Context)) *Route {
+	return s.PutRoute(http.MethodGet, path, handler)
 }
 
-func (s *Server) POST(path string, handler func(*Context)) {
-	s.PutRoute(http.MethodPost, path, handler)
+func (s *Server) POST(path string, handler func(*Context)) *Route {
+	return s.PutRoute(http.MethodPost, path, handler)
 }
 
-func (s *Server) PUT(path string, handler func(*Context)) {
-	s.PutRoute(http.MethodPut, path, handler)
+func (s *Server) PUT(path string, handler func(*Context)) *Route {
+	return s.PutRoute(http.MethodPut, path, handler)
 }
 
-func (s *Server) PATCH(path string, handler func(*Context)) {
-	s.PutRoute(http.MethodPatch, path, handler)
+func (s *Server) PATCH(path string, handler func(*Context)) *Route {
+	return s.PutRoute(http.MethodPatch, path, handler)
 }
 
-func (s *Server) DELETE(path string, handler func(*Context)) {
-	s.PutRoute(http.MethodDelete, path, handler)
+func (s *Server) DELETE(path string, handler func(*Context)) *Route {
+	return s.PutRoute(http.MethodDelete, path, handler)
 }
 
-func (s *Server) HEAD(path string, handler func(*Context)) {
-	s.PutRoute(http.MethodHead, path, handler)
+func (s *Server) HEAD(path string, handler func(*Context)) *Route {
+	return s.PutRoute(http.MethodHead, path, handler)
 }
 
-func (s *Server) OPTIONS(path string, handler func(*Context)) {
-	s.PutRoute(http.MethodOptions, path, handler)
+func (s *Server) OPTIONS(path string, handler func(*Context)) *Route {
+	return s.PutRoute(http.MethodOptions, path, handler)
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	c := newContext(w, req)
+	c := s.pool.Get().(*Context)
+	c.reset(w, req)
+	c.server = s
+	defer s.release(c)
 
 	defer func() {
 		if err := recover(); err != nil {
-			msg := strings.Builder{}
-			msg.WriteString(fmt.Sprintf("%s\n%s", err, "\nTraceback:"))
-			var pcs [32]uintptr
-			for _, pc := range pcs[:runtime.Callers(3, pcs[:])] {
-				fn := runtime.FuncForPC(pc)
-				file, line := fn.FileLine(pc)
-				msg.WriteString(fmt.Sprintf("\n\t%s:%d", file, line))
+			if s.PanicHandler != nil {
+				s.PanicHandler(c, err)
 			}
-			log.Printf("%s\n\n", msg.String())
-			c.String(http.StatusInternalServerError, "Internal Server Error")
+			s.handleError(c, NewHTTPError(http.StatusInternalServerError).WithError(fmt.Errorf("panic: %v", err)))
 		}
 	}()
 
-	handlerChain, params := s.rg.GetRoute(c.Method, c.Path)
+	handlerChain, params, route := s.rg.getRoute(c.Method, c.Path)
 	if len(handlerChain) > 0 {
 		c.setParams(params)
-		for _, handler := range handlerChain {
-			handler(c)
-		}
+		c.route = route
+		c.handlers, c.index = handlerChain, -1
+		c.Next()
 	} else {
-		c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+		c.Error(NewHTTPError(http.StatusNotFound, fmt.Sprintf("404 NOT FOUND: %s", c.Path)))
+	}
+	if c.err != nil {
+		s.handleError(c, c.err)
+	}
+}
+
+// release runs the finalizers, then returns the Context to the pool, it must not be used after the request anymore.
+func (s *Server) release(c *Context) {
+	c.finish()
+	s.pool.Put(c)
+}
+
+// Errors raised after the response is committed can not be rendered anymore, they are only passed to the handler for logging.
+// Otherwise what the writers hold back, like the beginning of a compressed body, is dropped for the error response.
+func (s *Server) handleError(c *Context, err error) {
+	if !c.Written() {
+		c.discardResponse()
+	}
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(c, err)
+	} else if !c.Written() {
+		http.Error(c.Writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 	}
 }
 
+// Listen serves on the address until Shutdown, it returns http.ErrServerClosed if Shutdown has already been called.
 func (s *Server) Listen(addr string) (err error) {
-	return http.ListenAndServe(addr, s)
+	s.srvMutex.Lock()
+	select {
+	case <-s.shutdown:
+		s.srvMutex.Unlock()
+		return http.ErrServerClosed
+	default:
+	}
+	srv := &http.Server{Addr: addr, Handler: s, ReadHeaderTimeout: s.ReadHeaderTimeout, ReadTimeout: s.ReadTimeout}
+	s.srv = srv
+	s.srvMutex.Unlock()
+	return srv.ListenAndServe()
+}
+
+// Shutdown signals the long-lived handlers to finish, then gracefully shuts down the listening server.
+func (s *Server) Shutdown(ctx context.Context) (err error) {
+	s.srvMutex.Lock()
+	s.shutdownOnce.Do(func() {
+		close(s.shutdown)
+	})
+	srv := s.srv
+	s.srvMutex.Unlock()
+	if srv != nil {
+		err = srv.Shutdown(ctx)
+	}
+	return
+}
+
+// ShuttingDown is closed once Shutdown is called.
+func (s *Server) ShuttingDown() <-chan struct{} {
+	return s.shutdown
 }
@@ -1,12 +1,164 @@
 package web
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(int) {}
+
 func TestNew(t *testing.T) {
 	s := New()
 	assert.NotNil(t, s)
 	assert.NotNil(t, s.rg)
 }
+
+func TestListenShutdown(t *testing.T) {
+	s := New()
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.ErrorIs(t, s.Listen("127.0.0.1:0"), http.ErrServerClosed)
+
+	// Shutdown stops the listener whichever of them runs first.
+	for i := 0; i < 10; i++ {
+		s = New()
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.Listen("127.0.0.1:0")
+		}()
+		assert.NoError(t, s.Shutdown(context.Background()))
+		select {
+		case err := <-errs:
+			assert.ErrorIs(t, err, http.ErrServerClosed)
+		case <-time.After(time.Second):
+			t.Fatal("Listen did not return after Shutdown")
+		}
+	}
+}
+
+func TestContextReset(t *testing.T) {
+	s := New()
+	s.GET("/set", func(c *Context) {
+		c.Set("user", "alice")
+		c.Defer(func() {})
+		c.Error(NewHTTPError(http.StatusTeapot))
+	})
+	s.GET("/get", func(c *Context) {
+		_, ok := c.Get("user")
+		assert.False(t, ok)
+		assert.Nil(t, c.err)
+		assert.Empty(t, c.finalizers)
+		c.String(http.StatusOK, "ok")
+	})
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/set", nil))
+		assert.Equal(t, http.StatusTeapot, w.Code)
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/get", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestFinalizerPanic(t *testing.T) {
+	var order []string
+	var panics []any
+	s := New()
+	s.PanicHandler = func(c *Context, err any) { panics = append(panics, err) }
+	s.GET("/", func(c *Context) {
+		c.Defer(func() { order = append(order, "first") })
+		c.Defer(func() { panic("boom") })
+		c.Defer(func() { order = append(order, "last") })
+		c.String(http.StatusOK, "ok")
+	})
+	w := httptest.NewRecorder()
+	assert.NotPanics(t, func() { s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil)) })
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"last", "first"}, order)
+	assert.Equal(t, []any{"boom"}, panics)
+}
+
+func TestHandlerChainInvalidation(t *testing.T) {
+	s := New()
+	g := s.Group("/api")
+	route := g.GET("/items", func(c *Context) {
+		c.String(http.StatusOK, "items")
+	})
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+		return w
+	}
+	assert.Equal(t, "items", serve().Body.String())
+
+	g.PreMiddlewares(func(c *Context) {
+		c.SetHeader("X-Group", "1")
+	})
+	assert.Equal(t, "1", serve().Header().Get("X-Group"))
+
+	s.PreMiddlewares(func(c *Context) {
+		c.SetHeader("X-Root", "1")
+	})
+	assert.Equal(t, "1", serve().Header().Get("X-Root"))
+
+	route.Use(func(c *Context) {
+		c.SetHeader("X-Route", "1")
+	})
+	assert.Equal(t, "1", serve().Header().Get("X-Route"))
+
+	g.UpdateRoute(http.MethodGet, "/items", func(c *Context) {
+		c.String(http.StatusOK, "updated")
+	})
+	assert.Equal(t, "updated", serve().Body.String())
+
+	g.RequireAuth()
+	assert.Equal(t, http.StatusUnauthorized, serve().Code)
+}
+
+func benchmarkServer(b *testing.B, path string) {
+	s := New()
+	s.PreMiddlewares(func(c *Context) {})
+	s.GET("/static/path", func(c *Context) {
+		c.Status(http.StatusOK)
+	})
+	s.GET(`/users/{(?P<id>\d+)}`, func(c *Context) {
+		c.Status(http.StatusOK)
+	})
+	s.Group("/api").PreMiddlewares(func(c *Context) {}).Group("/v1").PostMiddlewares(func(c *Context) {}).GET("/items", func(c *Context) {
+		c.Status(http.StatusOK)
+	})
+	w := &discardResponseWriter{header: http.Header{}}
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkServeStatic(b *testing.B) {
+	benchmarkServer(b, "/static/path")
+}
+
+func BenchmarkServeRegex(b *testing.B) {
+	benchmarkServer(b, "/users/42")
+}
+
+func BenchmarkServeGrouped(b *testing.B) {
+	benchmarkServer(b, "/api/v1/items")
+}
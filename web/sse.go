@@ -0,0 +1,160 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MIMETextEventStream = "text/event-stream"
+	HeaderLastEventID   = "Last-Event-ID"
+	HeaderCacheControl  = "Cache-Control"
+)
+
+var ErrSSEClosed = errors.New("event stream is closed")
+
+type (
+	// SSEvent is one message of the stream, Data is written as is if string or []byte, as JSON otherwise.
+	SSEvent struct {
+		ID    string
+		Event string
+		Data  any
+		Retry time.Duration
+	}
+
+	SSEStream struct {
+		c       *Context
+		flusher http.Flusher
+		done    chan struct{}
+		mutex   sync.Mutex
+		closed  bool
+	}
+)
+
+// SSE starts an event stream, which is closed when the client disconnects, the server shuts down or the handler returns.
+func (c *Context) SSE() (s *SSEStream, err error) {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		return nil, errors.New("the response writer does not implement http.Flusher")
+	}
+	h := c.Writer.Header()
+	h.Set(HeaderContentType, MIMETextEventStream)
+	h.Set(HeaderCacheControl, "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	flusher.Flush()
+	s = &SSEStream{c: c, flusher: flusher, done: make(chan struct{})}
+	var shutdown <-chan struct{}
+	if c.server != nil {
+		shutdown = c.server.ShuttingDown()
+	}
+	disconnected := c.Req.Context().Done()
+	go func() {
+		select {
+		case <-disconnected:
+		case <-shutdown:
+		case <-s.done:
+		}
+		s.Close()
+	}()
+	c.Defer(s.Close)
+	return
+}
+
+// LastEventID returns the ID of the last event received by the client before it reconnected, to resume the stream.
+func (s *SSEStream) LastEventID() string {
+	return s.c.Req.Header.Get(HeaderLastEventID)
+}
+
+// Done is closed once the stream is closed.
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *SSEStream) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+func (s *SSEStream) write(msg string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return ErrSSEClosed
+	}
+	if _, err := s.c.Writer.Write([]byte(msg)); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *SSEStream) Send(e SSEvent) error {
+	msg := strings.Builder{}
+	if e.ID != "" {
+		msg.WriteString("id: " + sseLine(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		msg.WriteString("event: " + sseLine(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		msg.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		msg.WriteString("data: " + line + "\n")
+	}
+	msg.WriteString("\n")
+	return s.write(msg.String())
+}
+
+// Comment sends a comment line, ignored by the client but keeping the connection alive through proxies.
+func (s *SSEStream) Comment(text string) error {
+	return s.write(": " + sseLine(text) + "\n\n")
+}
+
+// Heartbeat sends a comment at every interval until the stream is closed.
+func (s *SSEStream) Heartbeat(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if s.Comment("heartbeat") != nil {
+					return
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// The id, event and comment fields can not span several lines.
+func sseLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSESend(t *testing.T) {
+	s := New()
+	s.GET("/events", func(c *Context) {
+		stream, err := c.SSE()
+		assert.NoError(t, err)
+		assert.Equal(t, "41", stream.LastEventID())
+		assert.NoError(t, stream.Send(SSEvent{ID: "42", Event: "progress", Data: map[string]int{"done": 1}, Retry: 3 * time.Second}))
+		assert.NoError(t, stream.Send(SSEvent{Data: "line1\nline2"}))
+		assert.NoError(t, stream.Comment("ping"))
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set(HeaderLastEventID, "41")
+	s.ServeHTTP(w, req)
+	assert.Equal(t, MIMETextEventStream, w.Header().Get(HeaderContentType))
+	assert.Equal(t, "no-cache", w.Header().Get(HeaderCacheControl))
+	assert.Equal(t, "id: 42\nevent: progress\nretry: 3000\ndata: {\"done\":1}\n\ndata: line1\ndata: line2\n\n: ping\n\n", w.Body.String())
+}
+
+func TestSSEClose(t *testing.T) {
+	closed := make(chan error, 1)
+	s := New()
+	s.GET("/events", func(c *Context) {
+		stream, err := c.SSE()
+		assert.NoError(t, err)
+		stream.Heartbeat(time.Millisecond)
+		<-stream.Done()
+		closed <- stream.Send(SSEvent{Data: "late"})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx))
+	assert.ErrorIs(t, <-closed, ErrSSEClosed)
+	assert.True(t, strings.HasPrefix(w.Body.String(), ": heartbeat\n\n"))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, s.Shutdown(context.Background()))
+	}()
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.ErrorIs(t, <-closed, ErrSSEClosed)
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type (
+	valueStore struct {
+		values map[string]any
+		mutex  sync.RWMutex
+	}
+
+	// storeContext exposes the values of Context.Set to the code only holding the request context.
+	// It refers to the store rather than to the Context, which is reused by the next requests.
+	storeContext struct {
+		context.Context
+		store *valueStore
+	}
+)
+
+func (s *valueStore) get(key string) (value any, ok bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	value, ok = s.values[key]
+	return
+}
+
+func (ctx *storeContext) Value(key any) any {
+	if k, ok := key.(string); ok {
+		if v, found := ctx.store.get(k); found {
+			return v
+		}
+	}
+	return ctx.Context.Value(key)
+}
+
+// Set stores the value for the rest of the request, it can be read by Get or from the request context by the key.
+func (c *Context) Set(key string, value any) {
+	store := c.initStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.values[key] = value
+}
+
+// initStore creates the store with the first value, so that the requests without any do not allocate it.
+func (c *Context) initStore() *valueStore {
+	c.storeMu.Lock()
+	defer c.storeMu.Unlock()
+	if c.store == nil {
+		c.store = &valueStore{values: make(map[string]any)}
+		c.Req = c.Req.WithContext(&storeContext{Context: c.Req.Context(), store: c.store})
+	}
+	return c.store
+}
+
+func (c *Context) Get(key string) (value any, ok bool) {
+	c.storeMu.RLock()
+	store := c.store
+	c.storeMu.RUnlock()
+	if store == nil {
+		return
+	}
+	return store.get(key)
+}
+
+// MustGet panics if the key has not been set, which is turned into a 500 error by the server.
+func (c *Context) MustGet(key string) any {
+	value, ok := c.Get(key)
+	if !ok {
+		panic(fmt.Sprintf("key %q does not exist", key))
+	}
+	return value
+}
+
+// Value returns the value set for the key, ok is false if it is missing or not a T.
+func Value[T any](c *Context, key string) (value T, ok bool) {
+	v, found := c.Get(key)
+	if !found {
+		return
+	}
+	value, ok = v.(T)
+	return
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	s := New()
+	s.PreMiddlewares(func(c *Context) {
+		c.Set("user", "alice")
+		c.Set("tenant", 42)
+	}, RequestID())
+	s.GET("/", func(c *Context) {
+		readFromStd := func(ctx context.Context) any {
+			return ctx.Value("user")
+		}
+		tenant, ok := Value[int](c, "tenant")
+		assert.True(t, ok)
+		assert.Equal(t, 42, tenant)
+		_, ok = Value[string](c, "tenant")
+		assert.False(t, ok)
+		_, ok = Value[string](c, "missing")
+		assert.False(t, ok)
+		assert.Equal(t, "alice", c.MustGet("user"))
+		assert.Equal(t, "alice", readFromStd(c.Req.Context()))
+		assert.Equal(t, "alice", readFromStd(c))
+		assert.NotEmpty(t, RequestIDFromContext(c.Req.Context()))
+		c.Set("user", "bob")
+		assert.Equal(t, "bob", readFromStd(c.Req.Context()))
+		assert.Nil(t, c.Req.Context().Value("missing"))
+		c.String(http.StatusOK, "ok")
+	})
+	s.GET("/panic", func(c *Context) {
+		c.MustGet("missing")
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestGetWithoutSet(t *testing.T) {
+	c := newContext(nil, httptest.NewRequest(http.MethodGet, "/", nil))
+	v, ok := c.Get("user")
+	assert.Nil(t, v)
+	assert.False(t, ok)
+	assert.Nil(t, c.store)
+}
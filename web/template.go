@@ -0,0 +1,181 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+const (
+	TemplateExtension  = ".html"
+	TemplateLayoutDir  = "layouts"
+	TemplatePartialDir = "partials"
+)
+
+type (
+	// Renderer executes the named template with data into w.
+	Renderer interface {
+		Render(w io.Writer, name string, data any) error
+	}
+
+	TemplateConfig struct {
+		// FS to load the templates from, the directory Dir of the OS file system is used if nil.
+		FS  fs.FS
+		Dir string
+		// Extension of the template files, TemplateExtension by default.
+		Extension string
+		// Templates under LayoutDir and PartialDir are shared by all the pages, the other ones are the pages.
+		LayoutDir  string
+		PartialDir string
+		FuncMap    template.FuncMap
+		// Reload parses the templates again on every render, for the development mode.
+		Reload bool
+	}
+
+	// Templates parses every page with its own copy of the layouts and partials, so that pages can redefine the same blocks.
+	Templates struct {
+		cfg   TemplateConfig
+		fsys  fs.FS
+		pages map[string]*template.Template
+		mutex sync.RWMutex
+	}
+
+	// lazyStatusWriter commits the status on the first byte, so that an error before any output can still be rendered.
+	lazyStatusWriter struct {
+		c    *Context
+		code int
+	}
+)
+
+func NewTemplates(cfg TemplateConfig) (t *Templates, err error) {
+	if cfg.Extension == "" {
+		cfg.Extension = TemplateExtension
+	}
+	if cfg.LayoutDir == "" {
+		cfg.LayoutDir = TemplateLayoutDir
+	}
+	if cfg.PartialDir == "" {
+		cfg.PartialDir = TemplatePartialDir
+	}
+	t = &Templates{cfg: cfg, fsys: cfg.FS}
+	if t.fsys == nil {
+		if cfg.Dir == "" {
+			return nil, errors.New("template FS or Dir should be set")
+		}
+		t.fsys = os.DirFS(cfg.Dir)
+	}
+	if err = t.load(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// LoadTemplates sets the templates loaded from the config as the renderer of the server.
+func (s *Server) LoadTemplates(cfg TemplateConfig) error {
+	t, err := NewTemplates(cfg)
+	if err != nil {
+		return err
+	}
+	s.Renderer = t
+	return nil
+}
+
+func (t *Templates) isShared(name string) bool {
+	return strings.HasPrefix(name, t.cfg.LayoutDir+"/") || strings.HasPrefix(name, t.cfg.PartialDir+"/")
+}
+
+func (t *Templates) load() (err error) {
+	var shared, pages []string
+	err = fs.WalkDir(t.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != t.cfg.Extension {
+			return err
+		}
+		if t.isShared(name) {
+			shared = append(shared, name)
+		} else {
+			pages = append(pages, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	base := template.New("").Funcs(t.cfg.FuncMap)
+	for _, name := range shared {
+		if err = t.parse(base, name); err != nil {
+			return
+		}
+	}
+	parsed := make(map[string]*template.Template, len(pages))
+	for _, name := range pages {
+		var page *template.Template
+		if page, err = base.Clone(); err != nil {
+			return
+		}
+		if err = t.parse(page, name); err != nil {
+			return
+		}
+		parsed[name] = page
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.pages = parsed
+	return
+}
+
+func (t *Templates) parse(tpl *template.Template, name string) error {
+	b, err := fs.ReadFile(t.fsys, name)
+	if err != nil {
+		return err
+	}
+	_, err = tpl.New(name).Parse(string(b))
+	return err
+}
+
+// Render executes the page named by its path relative to the template root, like "users/show.html".
+func (t *Templates) Render(w io.Writer, name string, data any) error {
+	if t.cfg.Reload {
+		if err := t.load(); err != nil {
+			return err
+		}
+	}
+	t.mutex.RLock()
+	page, ok := t.pages[name]
+	t.mutex.RUnlock()
+	if !ok {
+		return fmt.Errorf("template %q not found", name)
+	}
+	return page.ExecuteTemplate(w, name, data)
+}
+
+func (w *lazyStatusWriter) Write(b []byte) (int, error) {
+	if w.code != 0 {
+		w.c.Status(w.code)
+		w.code = 0
+	}
+	return w.c.Writer.Write(b)
+}
+
+// Render streams the template through Server.Renderer, the errors are reported to Server.ErrorHandler.
+func (c *Context) Render(code int, name string, data any) {
+	if c.server == nil || c.server.Renderer == nil {
+		c.Error(NewHTTPError(http.StatusInternalServerError).WithError(errors.New("no renderer configured")))
+		return
+	}
+	c.SetHeader(HeaderContentType, "text/html; charset=utf-8")
+	w := &lazyStatusWriter{c: c, code: code}
+	if err := c.server.Renderer.Render(w, name, data); err != nil {
+		c.Error(NewHTTPError(http.StatusInternalServerError).WithError(err))
+		return
+	}
+	if w.code != 0 {
+		c.Status(code)
+	}
+}
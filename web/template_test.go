@@ -0,0 +1,61 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplatesRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html":    {Data: []byte(`<html>{{template "content" .}}{{template "partials/footer.html"}}</html>`)},
+		"partials/footer.html": {Data: []byte(`<footer>sampan</footer>`)},
+		"users/show.html":      {Data: []byte(`{{define "content"}}<h1>{{upper .Name}}</h1>{{end}}{{template "layouts/base.html" .}}`)},
+		"users/list.html":      {Data: []byte(`{{define "content"}}<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}{{template "layouts/base.html" .}}`)},
+		"broken.html":          {Data: []byte(`{{.Missing.Field}}`)},
+		"readme.txt":           {Data: []byte(`ignored`)},
+	}
+	s := New()
+	assert.NoError(t, s.LoadTemplates(TemplateConfig{FS: fsys, FuncMap: template.FuncMap{"upper": strings.ToUpper}}))
+	s.GET("/users/show", func(c *Context) { c.Render(http.StatusOK, "users/show.html", map[string]string{"Name": "<alice>"}) })
+	s.GET("/users/list", func(c *Context) { c.Render(http.StatusCreated, "users/list.html", []string{"a", "b"}) })
+	s.GET("/missing", func(c *Context) { c.Render(http.StatusOK, "missing.html", nil) })
+	s.GET("/broken", func(c *Context) { c.Render(http.StatusOK, "broken.html", 1) })
+	tcs := []struct {
+		path string
+		code int
+		body string
+	}{
+		{path: "/users/show", code: http.StatusOK, body: "<html><h1>&lt;ALICE&gt;</h1><footer>sampan</footer></html>"},
+		{path: "/users/list", code: http.StatusCreated, body: "<html><ul><li>a</li><li>b</li></ul><footer>sampan</footer></html>"},
+		{path: "/missing", code: http.StatusInternalServerError, body: "Internal Server Error\n"},
+		{path: "/broken", code: http.StatusInternalServerError, body: "Internal Server Error\n"},
+	}
+	for _, tc := range tcs {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Equal(t, tc.code, w.Code, tc.path)
+		assert.Equal(t, tc.body, w.Body.String(), tc.path)
+	}
+}
+
+func TestTemplatesReload(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "index.html")
+	assert.NoError(t, os.WriteFile(page, []byte(`v1`), 0o600))
+	tpl, err := NewTemplates(TemplateConfig{Dir: dir, Reload: true})
+	assert.NoError(t, err)
+	out := strings.Builder{}
+	assert.NoError(t, tpl.Render(&out, "index.html", nil))
+	assert.Equal(t, "v1", out.String())
+	assert.NoError(t, os.WriteFile(page, []byte(`v2`), 0o600))
+	out.Reset()
+	assert.NoError(t, tpl.Render(&out, "index.html", nil))
+	assert.Equal(t, "v2", out.String())
+}
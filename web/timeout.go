@@ -0,0 +1,194 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	TimeoutConfig struct {
+		Timeout time.Duration
+		// Code of the response sent once the timeout is reached, 503 by default.
+		Code int
+		// Message of the response sent once the timeout is reached, status text of Code by default.
+		Message string
+	}
+
+	// timeoutWriter holds the response of the handlers until they return, as http.TimeoutHandler does, so that only
+	// the request goroutine writes to the client. The handlers can not write anymore once the timeout is reached.
+	timeoutWriter struct {
+		http.ResponseWriter
+		header    http.Header
+		buf       bytes.Buffer
+		mutex     sync.Mutex
+		code      int
+		message   string
+		status    int
+		timedOut  bool
+		hijacked  bool
+		committed bool
+	}
+)
+
+func Timeout(timeout time.Duration) func(*Context) {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: timeout})
+}
+
+// TimeoutWithConfig runs the rest of the chain on another goroutine, on a copy of the Context, and answers with the
+// timeout response once the deadline is reached even if the handlers ignore the context. The response is held until
+// the handlers return, so it does not suit the streamed responses.
+func TimeoutWithConfig(cfg TimeoutConfig) func(*Context) {
+	if cfg.Timeout <= 0 {
+		panic("Timeout should be positive!")
+	}
+	if cfg.Code == 0 {
+		cfg.Code = http.StatusServiceUnavailable
+	}
+	if cfg.Message == "" {
+		cfg.Message = http.StatusText(cfg.Code)
+	}
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Req.Context(), cfg.Timeout)
+		tw := &timeoutWriter{ResponseWriter: c.Writer, header: c.Writer.Header().Clone(), code: cfg.Code, message: cfg.Message}
+		c.Writer, c.Req = tw, c.Req.WithContext(ctx)
+		c.Defer(func() {
+			tw.commit()
+			cancel()
+		})
+		f := c.fork()
+		f.c.setWriter(tw)
+		done := make(chan any, 1)
+		go func() {
+			defer func() {
+				done <- recover()
+			}()
+			f.run(func(fc *Context) {
+				fc.Next()
+			})
+		}()
+		select {
+		case err := <-done:
+			f.join()
+			if err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			// The handlers may have returned meanwhile.
+			if f.join() {
+				if err := <-done; err != nil {
+					panic(err)
+				}
+				return
+			}
+			tw.timeout(ctx.Err())
+			c.Abort()
+		}
+	}
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	if tw.status == 0 && !tw.timedOut && !tw.hijacked {
+		tw.status = code
+	}
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	switch {
+	case tw.timedOut:
+		return 0, http.ErrHandlerTimeout
+	case tw.hijacked:
+		return 0, http.ErrHijacked
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.buf.Write(b)
+}
+
+// Hijack hands the connection over unless the timeout response has been sent, which is then skipped.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	conn, brw, err := http.NewResponseController(tw.ResponseWriter).Hijack()
+	if err == nil {
+		tw.hijacked = true
+	}
+	return conn, brw, err
+}
+
+// timeout stops the writes of the handlers, then sends the timeout response if the deadline rather than the client
+// ended the request.
+func (tw *timeoutWriter) timeout(err error) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	tw.timedOut = true
+	if tw.hijacked || !errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	h := tw.ResponseWriter.Header()
+	h.Set(HeaderContentType, "text/plain; charset=utf-8")
+	h.Del(HeaderContentLength)
+	tw.ResponseWriter.WriteHeader(tw.code)
+	_, _ = tw.ResponseWriter.Write([]byte(tw.message))
+}
+
+// commit writes the held response once the request is completed, the header alone if nothing has been written.
+func (tw *timeoutWriter) commit() {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	if tw.timedOut || tw.hijacked || tw.committed {
+		return
+	}
+	tw.committed = true
+	dst := tw.ResponseWriter.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	if tw.status != 0 {
+		tw.ResponseWriter.WriteHeader(tw.status)
+	}
+	if tw.buf.Len() > 0 {
+		_, _ = tw.ResponseWriter.Write(tw.buf.Bytes())
+	}
+}
+
+// discard drops the held response, so that an error response replaces it.
+func (tw *timeoutWriter) discard() {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	if !tw.committed {
+		tw.buf.Reset()
+		tw.status = 0
+		tw.header.Del(HeaderContentLength)
+	}
+}
+
+func (tw *timeoutWriter) heldStatus() int {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	if tw.committed || tw.timedOut || tw.hijacked {
+		return 0
+	}
+	return tw.status
+}
+
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
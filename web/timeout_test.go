@@ -0,0 +1,105 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContextAsContext(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx, cancel := context.WithCancel(context.WithValue(req.Context(), requestIDKey{}, "abc"))
+	c := newContext(httptest.NewRecorder(), req.WithContext(ctx))
+	assert.Equal(t, "abc", RequestIDFromContext(c))
+	assert.NoError(t, c.Err())
+	cancel()
+	<-c.Done()
+	assert.ErrorIs(t, c.Err(), context.Canceled)
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	finished := make(chan error, 1)
+	s := New()
+	s.PreMiddlewares(TimeoutWithConfig(TimeoutConfig{Timeout: 20 * time.Millisecond, Code: http.StatusGatewayTimeout, Message: "too slow"}))
+	s.GET("/slow", func(c *Context) {
+		// The handler ignores the context, the response must not wait for it.
+		<-release
+		_, err := c.Writer.Write([]byte("late"))
+		finished <- err
+		c.Error(errors.New("late error"))
+	})
+	s.GET("/fast", func(c *Context) {
+		_, deadline := c.Deadline()
+		assert.True(t, deadline)
+		c.Set("key", "value")
+		c.String(http.StatusOK, "fast")
+	})
+	s.GET("/error", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		c.Error(NewHTTPError(http.StatusBadRequest))
+	})
+	s.GET("/panic", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, "too slow", w.Body.String())
+	close(release)
+	assert.ErrorIs(t, <-finished, http.ErrHandlerTimeout)
+
+	tcs := []struct {
+		path string
+		code int
+		body string
+	}{
+		{path: "/fast", code: http.StatusOK, body: "fast"},
+		{path: "/error", code: http.StatusBadRequest, body: "Bad Request\n"},
+		{path: "/panic", code: http.StatusInternalServerError, body: "Internal Server Error\n"},
+	}
+	for _, tc := range tcs {
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Equal(t, tc.code, w.Code, tc.path)
+		assert.Equal(t, tc.body, w.Body.String(), tc.path)
+		assert.Equal(t, "text/plain", w.Header().Get(HeaderContentType), tc.path)
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return r.conn, bufio.NewReadWriter(bufio.NewReader(r.conn), bufio.NewWriter(r.conn)), nil
+}
+
+func TestTimeoutHijacked(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	hijacked := make(chan struct{})
+	s := New()
+	s.PreMiddlewares(Timeout(20 * time.Millisecond))
+	s.GET("/ws", func(c *Context) {
+		conn, _, err := http.NewResponseController(c.Writer).Hijack()
+		assert.NoError(t, err)
+		close(hijacked)
+		<-c.Done()
+		_ = conn.Close()
+	})
+	w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	<-hijacked
+	assert.False(t, w.Flushed)
+	assert.Empty(t, w.Body.String())
+	assert.Empty(t, w.Header().Get(HeaderContentType))
+}
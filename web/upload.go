@@ -0,0 +1,293 @@
+package web
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	UploadMaxMemory = 32 << 20
+	sniffLength     = 512
+)
+
+type (
+	UploadConfig struct {
+		// MaxBodyBytes caps the whole request body like BodyLimit, no limit if zero.
+		MaxBodyBytes int64
+		// MaxFileBytes caps every file of the form, no limit if zero.
+		MaxFileBytes int64
+		// MaxMemory of MultipartForm, the rest of the files is stored on disk, UploadMaxMemory by default.
+		MaxMemory int64
+		// AllowedTypes of the files, sniffed from their content, "type/*" matches any subtype, any type if empty.
+		AllowedTypes []string
+	}
+
+	// UploadPart is a part of a streamed multipart body, reading it fails once the file exceeds MaxFileBytes.
+	UploadPart struct {
+		*multipart.Part
+		// ContentType sniffed from the first bytes of a file part, empty for the other fields.
+		ContentType string
+		r           io.Reader
+	}
+
+	limitedFileReader struct {
+		r     io.Reader
+		limit int64
+		read  int64
+	}
+)
+
+// Upload sets the limits of the upload helpers of Context for the routes it is used on.
+func Upload(cfg UploadConfig) func(*Context) {
+	if cfg.MaxMemory == 0 {
+		cfg.MaxMemory = UploadMaxMemory
+	}
+	limit := BodyLimit(cfg.MaxBodyBytes)
+	return func(c *Context) {
+		c.upload = &cfg
+		limit(c)
+	}
+}
+
+func (c *Context) uploadConfig() *UploadConfig {
+	if c.upload == nil {
+		c.upload = &UploadConfig{MaxMemory: UploadMaxMemory}
+	}
+	return c.upload
+}
+
+// uploadError turns the errors of the body reading into the HTTP errors to answer.
+func uploadError(err error) error {
+	var mbe *http.MaxBytesError
+	var he *HTTPError
+	switch {
+	case err == nil, errors.As(err, &he):
+		return err
+	case errors.As(err, &mbe):
+		return NewHTTPError(http.StatusRequestEntityTooLarge).WithError(err)
+	case errors.Is(err, http.ErrNotMultipart), errors.Is(err, http.ErrMissingBoundary), errors.Is(err, http.ErrMissingFile):
+		return NewHTTPError(http.StatusBadRequest, err.Error()).WithError(err)
+	}
+	return NewHTTPError(http.StatusBadRequest).WithError(err)
+}
+
+func (cfg *UploadConfig) checkType(contentType string) error {
+	if len(cfg.AllowedTypes) > 0 && !matchContentType(contentType, cfg.AllowedTypes) {
+		return NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("file type %s is not allowed", contentType))
+	}
+	return nil
+}
+
+func sniffFile(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, b)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return http.DetectContentType(b[:n]), nil
+}
+
+// MultipartForm parses the whole form, then checks the size and the sniffed type of every file.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	cfg := c.uploadConfig()
+	if c.Req.MultipartForm == nil {
+		if err := c.parseMultipartForm(cfg); err != nil {
+			return nil, uploadError(err)
+		}
+		for _, fhs := range c.Req.MultipartForm.File {
+			for _, fh := range fhs {
+				contentType, err := sniffFile(fh)
+				if err != nil {
+					return nil, uploadError(err)
+				}
+				if err = cfg.checkType(contentType); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// parseMultipartForm is Request.ParseMultipartForm with the files limited to MaxFileBytes while they are read rather
+// than once they are buffered, the parts are streamed through the limit to the multipart.Reader building the form.
+func (c *Context) parseMultipartForm(cfg *UploadConfig) error {
+	if err := c.Req.ParseForm(); err != nil {
+		return err
+	}
+	mr, err := c.Req.MultipartReader()
+	if err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	boundary, done := mw.Boundary(), make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = pw.CloseWithError(copyParts(mw, mr, cfg.MaxFileBytes))
+	}()
+	form, err := multipart.NewReader(pr, boundary).ReadForm(cfg.MaxMemory)
+	// stops the copy if the form has failed first, the body must not be read once the handler has returned.
+	_ = pr.CloseWithError(err)
+	<-done
+	if err != nil {
+		return err
+	}
+	for k, v := range form.Value {
+		c.Req.Form[k] = append(c.Req.Form[k], v...)
+		c.Req.PostForm[k] = append(c.Req.PostForm[k], v...)
+	}
+	// net/http only removes the files of the original request, which c.Req may have replaced by a copy.
+	c.Defer(func() {
+		_ = form.RemoveAll()
+	})
+	c.Req.MultipartForm = form
+	return nil
+}
+
+func copyParts(mw *multipart.Writer, mr *multipart.Reader, maxFileBytes int64) error {
+	for {
+		part, err := mr.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return mw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		w, err := mw.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+		var r io.Reader = part
+		if maxFileBytes > 0 && part.FileName() != "" {
+			r = &limitedFileReader{r: part, limit: maxFileBytes}
+		}
+		if _, err = io.Copy(w, r); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	if fhs := form.File[name]; len(fhs) > 0 {
+		return fhs[0], nil
+	}
+	return nil, uploadError(http.ErrMissingFile)
+}
+
+// Parts streams the parts of the multipart body without buffering the files, the iteration stops on the first error.
+func (c *Context) Parts() iter.Seq2[*UploadPart, error] {
+	return func(yield func(*UploadPart, error) bool) {
+		cfg := c.uploadConfig()
+		mr, err := c.Req.MultipartReader()
+		if err != nil {
+			yield(nil, uploadError(err))
+			return
+		}
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, uploadError(err))
+				return
+			}
+			up := &UploadPart{Part: part, r: part}
+			if part.FileName() != "" {
+				var r io.Reader = part
+				if cfg.MaxFileBytes > 0 {
+					r = &limitedFileReader{r: part, limit: cfg.MaxFileBytes}
+				}
+				br := bufio.NewReaderSize(r, sniffLength)
+				b, err := br.Peek(sniffLength)
+				if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+					yield(nil, uploadError(err))
+					return
+				}
+				up.ContentType, up.r = http.DetectContentType(b), br
+				if err = cfg.checkType(up.ContentType); err != nil {
+					yield(nil, err)
+					return
+				}
+			}
+			if !yield(up, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (p *UploadPart) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+func (r *limitedFileReader) Read(b []byte) (n int, err error) {
+	n, err = r.r.Read(b)
+	if r.read += int64(n); r.read > r.limit {
+		return n, NewHTTPError(http.StatusRequestEntityTooLarge, "file is too large")
+	}
+	return
+}
+
+// SanitizeFilename keeps the base name of the client filename, without separators, control characters nor leading dots.
+func SanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' || r == ':' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if name == "" {
+		name = "upload"
+	}
+	return name
+}
+
+// SaveUploadedFile saves the file in the directory under its sanitized name, it fails rather than overwriting an existing file
+// and removes the partly written file on failure.
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dir string) (dst string, err error) {
+	dst = filepath.Join(dir, SanitizeFilename(fh.Filename))
+	if rel, e := filepath.Rel(dir, dst); e != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("invalid upload filename %q", fh.Filename)
+	}
+	src, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if e := out.Close(); err == nil {
+			err = e
+		}
+		if err != nil {
+			_ = os.Remove(dst)
+			dst = ""
+		}
+	}()
+	_, err = io.Copy(out, src)
+	return
+}
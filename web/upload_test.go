@@ -0,0 +1,188 @@
+package web
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func multipartBody(t *testing.T, files map[string]string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	assert.NoError(t, w.WriteField("title", "report"))
+	for name, content := range files {
+		f, err := w.CreateFormFile("file", name)
+		assert.NoError(t, err)
+		_, _ = f.Write([]byte(content))
+	}
+	assert.NoError(t, w.Close())
+	return body, w.FormDataContentType()
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tcs := map[string]string{
+		"report.pdf":          "report.pdf",
+		"../../etc/passwd":    "passwd",
+		"..\\..\\win.ini":     "win.ini",
+		".htaccess":           "htaccess",
+		"a\x00b\nc.txt":       "abc.txt",
+		"..":                  "upload",
+		"/":                   "upload",
+		"dir/sub/../name.txt": "name.txt",
+	}
+	for name, expected := range tcs {
+		assert.Equal(t, expected, SanitizeFilename(name), name)
+	}
+}
+
+func TestFormFileAndSave(t *testing.T) {
+	dir := t.TempDir()
+	s := New()
+	s.POST("/upload", HandleError(func(c *Context) error {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return err
+		}
+		dst, err := c.SaveUploadedFile(fh, dir)
+		if err != nil {
+			return err
+		}
+		c.String(http.StatusCreated, filepath.Base(dst))
+		return nil
+	})).Use(Upload(UploadConfig{MaxBodyBytes: 1024, MaxFileBytes: 100, AllowedTypes: []string{"text/*"}}))
+	tcs := []struct {
+		filename string
+		content  string
+		code     int
+		body     string
+	}{
+		{filename: "../notes.txt", content: "hello", code: http.StatusCreated, body: "notes.txt"},
+		{filename: "big.txt", content: strings.Repeat("a", 101), code: http.StatusRequestEntityTooLarge},
+		{filename: "huge.txt", content: strings.Repeat("a", 2048), code: http.StatusRequestEntityTooLarge},
+		{filename: "image.txt", content: "\x89PNG\r\n\x1a\n0000", code: http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range tcs {
+		body, contentType := multipartBody(t, map[string]string{tc.filename: tc.content})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set(HeaderContentType, contentType)
+		s.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, tc.filename)
+		if tc.body != "" {
+			assert.Equal(t, tc.body, w.Body.String())
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+}
+
+func TestParts(t *testing.T) {
+	s := New()
+	s.POST("/stream", HandleError(func(c *Context) error {
+		var out []string
+		for part, err := range c.Parts() {
+			if err != nil {
+				return err
+			}
+			b, err := io.ReadAll(part)
+			if err != nil {
+				return err
+			}
+			out = append(out, part.FormName()+":"+part.ContentType+":"+string(b))
+		}
+		c.String(http.StatusOK, strings.Join(out, "|"))
+		return nil
+	})).Use(Upload(UploadConfig{MaxFileBytes: 1000}))
+	body, contentType := multipartBody(t, map[string]string{"a.txt": "hello"})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/stream", body)
+	req.Header.Set(HeaderContentType, contentType)
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "title::report|file:text/plain; charset=utf-8:hello", w.Body.String())
+
+	body, contentType = multipartBody(t, map[string]string{"a.txt": strings.Repeat("a", 1001)})
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/stream", body)
+	req.Header.Set(HeaderContentType, contentType)
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/stream", strings.NewReader("x")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+type countingReader struct {
+	r    io.Reader
+	read int
+}
+
+func (r *countingReader) Read(b []byte) (n int, err error) {
+	n, err = r.r.Read(b)
+	r.read += n
+	return
+}
+
+func TestMultipartFormFileLimit(t *testing.T) {
+	s := New()
+	s.POST("/upload", HandleError(func(c *Context) error {
+		form, err := c.MultipartForm()
+		if err != nil {
+			return err
+		}
+		c.String(http.StatusOK, form.Value["title"][0]+":"+c.Req.PostFormValue("title"))
+		return nil
+	})).Use(Upload(UploadConfig{MaxFileBytes: 100}))
+	body, contentType := multipartBody(t, map[string]string{"a.txt": "hello"})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set(HeaderContentType, contentType)
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "report:report", w.Body.String())
+
+	// the file is rejected once it exceeds the limit, without reading the rest of the body.
+	body, contentType = multipartBody(t, map[string]string{"big.txt": strings.Repeat("a", 10<<20)})
+	r := &countingReader{r: body}
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/upload", r)
+	req.Header.Set(HeaderContentType, contentType)
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Less(t, r.read, 1<<20)
+}
+
+func TestMultipartFormRemoveAll(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	s := New()
+	// RequestID replaces the request by a copy, whose form net/http does not know about.
+	s.PreMiddlewares(RequestID())
+	s.POST("/upload", HandleError(func(c *Context) error {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return err
+		}
+		c.String(http.StatusOK, "%d", fh.Size)
+		return nil
+	})).Use(Upload(UploadConfig{MaxMemory: 1}))
+	body, contentType := multipartBody(t, map[string]string{"a.txt": strings.Repeat("a", 100<<10)})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set(HeaderContentType, contentType)
+	s.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "102400", w.Body.String())
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,557 @@
+package web
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Opcodes of the frames, as defined by RFC 6455.
+	ContinuationMessage = 0
+	TextMessage         = 1
+	BinaryMessage       = 2
+	CloseMessage        = 8
+	PingMessage         = 9
+	PongMessage         = 10
+
+	// Status codes of the close frames.
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseNoStatusReceived = 1005
+	CloseInvalidPayload   = 1007
+	CloseMessageTooBig    = 1009
+
+	WebSocketReadLimit    = 1 << 20
+	WebSocketWriteTimeout = 10 * time.Second
+	WebSocketCloseTimeout = time.Second
+	HubQueueSize          = 64
+	webSocketGUID         = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	maxControlPayload     = 125
+)
+
+var (
+	ErrWebSocketReadLimit = errors.New("websocket: message exceeds the read limit")
+	ErrWebSocketClosed    = errors.New("websocket: connection is closed")
+)
+
+type (
+	UpgradeConfig struct {
+		// ReadLimit is the maximum size of a message in bytes, WebSocketReadLimit by default.
+		ReadLimit int64
+		// MaxFrameSize splits the written messages in fragments of this size, no fragmentation if zero.
+		MaxFrameSize int
+		// CheckOrigin accepts the request, only the origin of the request host is accepted by default.
+		CheckOrigin func(r *http.Request) bool
+		// Subprotocols supported by the server, by order of preference.
+		Subprotocols []string
+		// PingInterval enables the keepalive, the connection is closed if nothing is received within PingInterval+PongWait.
+		PingInterval time.Duration
+		PongWait     time.Duration
+		// WriteTimeout of every frame, WebSocketWriteTimeout by default.
+		WriteTimeout time.Duration
+	}
+
+	CloseError struct {
+		Code   int
+		Reason string
+	}
+
+	// Conn is a server side WebSocket connection, ReadMessage must be called by one goroutine at a time,
+	// the writes are safe for concurrent use.
+	Conn struct {
+		conn        net.Conn
+		br          *bufio.Reader
+		cfg         UpgradeConfig
+		subprotocol string
+		wmutex      sync.Mutex
+		closeOnce   sync.Once
+		closed      chan struct{}
+		closeSent   bool
+	}
+
+	HubConfig struct {
+		// QueueSize is the number of messages waiting to be written to every connection, HubQueueSize by default.
+		QueueSize int
+	}
+
+	// Hub keeps groups of connections to broadcast messages to, every connection is written by its own goroutine
+	// from a queue, so that a slow peer does not delay the others.
+	Hub struct {
+		cfg    HubConfig
+		groups map[string]map[*Conn]struct{}
+		queues map[*Conn]chan hubMessage
+		mutex  sync.RWMutex
+	}
+
+	hubMessage struct {
+		messageType int
+		data        []byte
+	}
+)
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Reason)
+}
+
+func webSocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContainsToken(h http.Header, key, token string) bool {
+	for _, v := range h.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get(HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// Upgrade performs the opening handshake and hijacks the connection, which is closed once the handler returns.
+func (c *Context) Upgrade(configs ...UpgradeConfig) (conn *Conn, err error) {
+	var cfg UpgradeConfig
+	if len(configs) > 0 {
+		cfg = configs[0]
+	}
+	if cfg.ReadLimit == 0 {
+		cfg.ReadLimit = WebSocketReadLimit
+	}
+	if cfg.CheckOrigin == nil {
+		cfg.CheckOrigin = sameOrigin
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = WebSocketWriteTimeout
+	}
+	r := c.Req
+	if r.Method != http.MethodGet || !headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!headerContainsToken(r.Header, "Upgrade", "websocket") {
+		err = NewHTTPError(http.StatusBadRequest, "not a websocket handshake")
+	} else if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		err = NewHTTPError(http.StatusUpgradeRequired, "unsupported websocket version")
+	} else if key, e := base64.StdEncoding.DecodeString(r.Header.Get("Sec-WebSocket-Key")); e != nil || len(key) != 16 {
+		err = NewHTTPError(http.StatusBadRequest, "invalid websocket key")
+	} else if !cfg.CheckOrigin(r) {
+		err = NewHTTPError(http.StatusForbidden, "origin not allowed")
+	}
+	if err != nil {
+		c.Error(err)
+		return nil, err
+	}
+	var subprotocol string
+	for _, p := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		if p = strings.TrimSpace(p); slices.Contains(cfg.Subprotocols, p) {
+			subprotocol = p
+			break
+		}
+	}
+	netConn, brw, err := http.NewResponseController(c.Writer).Hijack()
+	if err != nil {
+		c.Error(err)
+		return nil, err
+	}
+	handshake := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + webSocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n"
+	if subprotocol != "" {
+		handshake += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	_ = netConn.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+	if _, err = netConn.Write([]byte(handshake + "\r\n")); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	conn = &Conn{conn: netConn, br: brw.Reader, cfg: cfg, subprotocol: subprotocol, closed: make(chan struct{})}
+	conn.extendReadDeadline()
+	if cfg.PingInterval > 0 {
+		go conn.keepalive()
+	}
+	c.Defer(conn.closeNow)
+	return
+}
+
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Done is closed once the underlying connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.closed
+}
+
+func (c *Conn) closeNow() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		_ = c.conn.Close()
+	})
+}
+
+func (c *Conn) extendReadDeadline() {
+	if c.cfg.PingInterval > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.cfg.PingInterval + c.cfg.PongWait))
+	}
+}
+
+func (c *Conn) keepalive() {
+	ticker := time.NewTicker(c.cfg.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if c.Ping(nil) != nil {
+				c.closeNow()
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func (c *Conn) writeFrame(fin bool, opcode int, payload []byte) error {
+	header := make([]byte, 2, 10)
+	if fin {
+		header[0] = 0x80
+	}
+	header[0] |= byte(opcode)
+	switch l := len(payload); {
+	case l <= maxControlPayload:
+		header[1] = byte(l)
+	case l <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(l))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(l))
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+	if c.closeSent {
+		return ErrWebSocketClosed
+	}
+	opcode, size := messageType, c.cfg.MaxFrameSize
+	for size > 0 && len(data) > size {
+		if err := c.writeFrame(false, opcode, data[:size]); err != nil {
+			return err
+		}
+		opcode, data = ContinuationMessage, data[size:]
+	}
+	return c.writeFrame(true, opcode, data)
+}
+
+func (c *Conn) WriteText(text string) error {
+	return c.WriteMessage(TextMessage, []byte(text))
+}
+
+func (c *Conn) writeControl(opcode int, payload []byte) error {
+	if len(payload) > maxControlPayload {
+		return errors.New("websocket: control frame payload is too large")
+	}
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+	if c.closeSent {
+		return ErrWebSocketClosed
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+	return c.writeFrame(true, opcode, payload)
+}
+
+func (c *Conn) Ping(data []byte) error {
+	return c.writeControl(PingMessage, data)
+}
+
+func closePayload(code int, reason string) []byte {
+	if code == CloseNoStatusReceived {
+		return nil
+	}
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)
+}
+
+// Close performs the closing handshake, it waits for the close frame of the peer then closes the connection.
+// It must not be called while another goroutine is in ReadMessage.
+func (c *Conn) Close(code int, reason string) (err error) {
+	defer c.closeNow()
+	if err = c.writeControl(CloseMessage, closePayload(code, reason)); err != nil {
+		return
+	}
+	_ = c.conn.SetReadDeadline(time.Now().Add(WebSocketCloseTimeout))
+	for {
+		if _, _, err = c.ReadMessage(); err != nil {
+			var ce *CloseError
+			if errors.As(err, &ce) {
+				err = nil
+			}
+			return
+		}
+	}
+}
+
+// fail closes the connection with the status code after a protocol violation of the peer.
+func (c *Conn) fail(code int, err error) error {
+	_ = c.writeControl(CloseMessage, closePayload(code, err.Error()))
+	c.closeNow()
+	return err
+}
+
+func (c *Conn) readFrame(limit int64) (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return
+	}
+	c.extendReadDeadline()
+	fin, opcode = header[0]&0x80 != 0, int(header[0]&0x0f)
+	masked, length := header[1]&0x80 != 0, int64(header[1]&0x7f)
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, errors.New("websocket: reserved bits are set"))
+	}
+	if !masked {
+		return false, 0, nil, c.fail(CloseProtocolError, errors.New("websocket: client frame is not masked"))
+	}
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		if length = int64(binary.BigEndian.Uint64(ext[:])); length < 0 {
+			return false, 0, nil, c.fail(CloseProtocolError, errors.New("websocket: invalid payload length"))
+		}
+	}
+	if opcode >= CloseMessage {
+		if !fin || length > maxControlPayload {
+			return false, 0, nil, c.fail(CloseProtocolError, errors.New("websocket: invalid control frame"))
+		}
+	} else if length > limit {
+		return false, 0, nil, c.fail(CloseMessageTooBig, ErrWebSocketReadLimit)
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// ReadMessage returns the next text or binary message, reassembling the fragments and answering the control frames.
+// A close frame of the peer is answered and returned as *CloseError.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		fin, opcode, payload, err := c.readFrame(c.cfg.ReadLimit - int64(len(data)))
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				err = ErrWebSocketClosed
+			}
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err = c.writeControl(PongMessage, payload); err != nil && !errors.Is(err, ErrWebSocketClosed) {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			ce := &CloseError{Code: CloseNoStatusReceived}
+			if len(payload) >= 2 {
+				ce.Code, ce.Reason = int(binary.BigEndian.Uint16(payload)), string(payload[2:])
+			}
+			_ = c.writeControl(CloseMessage, closePayload(ce.Code, ""))
+			c.closeNow()
+			return 0, nil, ce
+		case ContinuationMessage:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, errors.New("websocket: unexpected continuation frame"))
+			}
+			data = append(data, payload...)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, errors.New("websocket: expected continuation frame"))
+			}
+			messageType, data = opcode, payload
+		default:
+			return 0, nil, c.fail(CloseProtocolError, fmt.Errorf("websocket: unknown opcode %d", opcode))
+		}
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(data) {
+				return 0, nil, c.fail(CloseInvalidPayload, errors.New("websocket: invalid UTF-8 text"))
+			}
+			return messageType, data, nil
+		}
+	}
+}
+
+func NewHub() *Hub {
+	return NewHubWithConfig(HubConfig{})
+}
+
+func NewHubWithConfig(cfg HubConfig) *Hub {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = HubQueueSize
+	}
+	return &Hub{cfg: cfg, groups: make(map[string]map[*Conn]struct{}), queues: make(map[*Conn]chan hubMessage)}
+}
+
+func (h *Hub) Join(group string, c *Conn) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.groups[group]; !ok {
+		h.groups[group] = make(map[*Conn]struct{})
+	}
+	h.groups[group][c] = struct{}{}
+	if _, ok := h.queues[c]; !ok {
+		queue := make(chan hubMessage, h.cfg.QueueSize)
+		h.queues[c] = queue
+		go h.send(c, queue)
+	}
+}
+
+func (h *Hub) Leave(group string, c *Conn) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if conns, ok := h.groups[group]; ok {
+		if delete(conns, c); len(conns) == 0 {
+			delete(h.groups, group)
+		}
+	}
+	for _, conns := range h.groups {
+		if _, ok := conns[c]; ok {
+			return
+		}
+	}
+	h.removeQueue(c)
+}
+
+func (h *Hub) LeaveAll(c *Conn) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.leaveAll(c)
+}
+
+func (h *Hub) leaveAll(c *Conn) {
+	for group, conns := range h.groups {
+		if delete(conns, c); len(conns) == 0 {
+			delete(h.groups, group)
+		}
+	}
+	h.removeQueue(c)
+}
+
+// removeQueue stops the writing goroutine of the connection once it has written the queued messages.
+func (h *Hub) removeQueue(c *Conn) {
+	if queue, ok := h.queues[c]; ok {
+		close(queue)
+		delete(h.queues, c)
+	}
+}
+
+func (h *Hub) Len(group string) int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.groups[group])
+}
+
+// Broadcast queues the message for every connection of the group without waiting for them to be written.
+// The connections whose queue is full are too slow to keep up, they are closed and leave the hub, as do the ones failing.
+func (h *Hub) Broadcast(group string, messageType int, data []byte) {
+	m := hubMessage{messageType: messageType, data: slices.Clone(data)}
+	var slow []*Conn
+	h.mutex.RLock()
+	for c := range h.groups[group] {
+		select {
+		case h.queues[c] <- m:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mutex.RUnlock()
+	for _, c := range slow {
+		h.drop(c, nil)
+		c.closeNow()
+	}
+}
+
+// send writes the queued messages to the connection until it leaves the hub, fails or is closed.
+func (h *Hub) send(c *Conn, queue chan hubMessage) {
+	for {
+		select {
+		case m, ok := <-queue:
+			if !ok {
+				return
+			}
+			if err := c.WriteMessage(m.messageType, m.data); err != nil {
+				h.drop(c, queue)
+				return
+			}
+		case <-c.Done():
+			h.drop(c, queue)
+			return
+		}
+	}
+}
+
+// drop removes the connection from the hub, unless it has left and joined again with another queue meanwhile.
+func (h *Hub) drop(c *Conn, queue chan hubMessage) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if current, ok := h.queues[c]; ok && (queue == nil || current == queue) {
+		h.leaveAll(c)
+	}
+}
@@ -0,0 +1,209 @@
+package web
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testWSClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialTestWS(t *testing.T, server *httptest.Server, path string, header http.Header) (*testWSClient, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	assert.NoError(t, req.Write(conn))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	assert.NoError(t, err)
+	return &testWSClient{conn: conn, br: br}, resp
+}
+
+func (c *testWSClient) write(fin bool, opcode int, payload []byte) {
+	header := []byte{byte(opcode), 0x80}
+	if fin {
+		header[0] |= 0x80
+	}
+	if len(payload) <= 125 {
+		header[1] |= byte(len(payload))
+	} else {
+		header[1] |= 126
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	}
+	mask := make([]byte, 4)
+	_, _ = rand.Read(mask)
+	masked := make([]byte, len(payload))
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+	_, _ = c.conn.Write(append(append(header, mask...), masked...))
+}
+
+func (c *testWSClient) read() (fin bool, opcode int, payload []byte) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.br, header); err != nil {
+		return
+	}
+	fin, opcode = header[0]&0x80 != 0, int(header[0]&0x0f)
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		ext := make([]byte, 2)
+		_, _ = io.ReadFull(c.br, ext)
+		length = int(binary.BigEndian.Uint16(ext))
+	}
+	payload = make([]byte, length)
+	_, _ = io.ReadFull(c.br, payload)
+	return
+}
+
+func TestWebSocketAccept(t *testing.T) {
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", webSocketAccept("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestWebSocketUpgradeRejected(t *testing.T) {
+	s := New()
+	s.GET("/ws", func(c *Context) { _, _ = c.Upgrade() })
+	server := httptest.NewServer(s)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/ws")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	client, resp := dialTestWS(t, server, "/ws", http.Header{"Origin": {"https://evil.com"}})
+	defer client.conn.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	hub := NewHub()
+	s := New()
+	s.GET("/ws", func(c *Context) {
+		conn, err := c.Upgrade(UpgradeConfig{ReadLimit: 200, MaxFrameSize: 4, Subprotocols: []string{"chat"}})
+		if err != nil {
+			return
+		}
+		hub.Join("room", conn)
+		defer hub.LeaveAll(conn)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(data) == "broadcast" {
+				hub.Broadcast("room", TextMessage, []byte("all"))
+			} else {
+				_ = conn.WriteMessage(messageType, data)
+			}
+		}
+	})
+	server := httptest.NewServer(s)
+	defer server.Close()
+	client, resp := dialTestWS(t, server, "/ws", http.Header{"Sec-WebSocket-Protocol": {"other, chat"}})
+	defer client.conn.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "chat", resp.Header.Get("Sec-WebSocket-Protocol"))
+
+	// fragmented message with an interleaved ping, echoed in frames of 4 bytes.
+	client.write(false, TextMessage, []byte("hello "))
+	client.write(true, PingMessage, []byte("p"))
+	client.write(true, ContinuationMessage, []byte("world"))
+	fin, opcode, payload := client.read()
+	assert.Equal(t, []any{true, PongMessage, "p"}, []any{fin, opcode, string(payload)})
+	var message []byte
+	for i := 0; !fin || i == 0; i++ {
+		fin, opcode, payload = client.read()
+		if i == 0 {
+			assert.Equal(t, TextMessage, opcode)
+		} else {
+			assert.Equal(t, ContinuationMessage, opcode)
+		}
+		message = append(message, payload...)
+	}
+	assert.Equal(t, "hello world", string(message))
+
+	client.write(true, TextMessage, []byte("broadcast"))
+	_, _, payload = client.read()
+	assert.Equal(t, "all", string(payload))
+
+	client.write(true, BinaryMessage, make([]byte, 201))
+	_, opcode, payload = client.read()
+	assert.Equal(t, CloseMessage, opcode)
+	assert.Equal(t, CloseMessageTooBig, int(binary.BigEndian.Uint16(payload)))
+	<-time.After(10 * time.Millisecond)
+	assert.Equal(t, 0, hub.Len("room"))
+}
+
+func TestWebSocketCloseHandshake(t *testing.T) {
+	done := make(chan error, 1)
+	s := New()
+	s.GET("/ws", func(c *Context) {
+		conn, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		_, _, err = conn.ReadMessage()
+		done <- err
+	})
+	server := httptest.NewServer(s)
+	defer server.Close()
+	client, _ := dialTestWS(t, server, "/ws", nil)
+	defer client.conn.Close()
+	client.write(true, CloseMessage, append(binary.BigEndian.AppendUint16(nil, CloseGoingAway), "bye"...))
+	_, opcode, payload := client.read()
+	assert.Equal(t, CloseMessage, opcode)
+	assert.Equal(t, CloseGoingAway, int(binary.BigEndian.Uint16(payload)))
+	assert.Equal(t, &CloseError{Code: CloseGoingAway, Reason: "bye"}, <-done)
+}
+
+func TestHubSlowPeer(t *testing.T) {
+	newPipeConn := func() (*Conn, net.Conn) {
+		server, client := net.Pipe()
+		return &Conn{conn: server, cfg: UpgradeConfig{WriteTimeout: time.Minute}, closed: make(chan struct{})}, client
+	}
+	hub := NewHubWithConfig(HubConfig{QueueSize: 2})
+	fast, fastClient := newPipeConn()
+	slow, slowClient := newPipeConn()
+	defer fastClient.Close()
+	defer slowClient.Close()
+	hub.Join("room", fast)
+	hub.Join("room", slow)
+	received := make(chan []byte, 10)
+	go func() {
+		client := &testWSClient{conn: fastClient, br: bufio.NewReader(fastClient)}
+		for {
+			_, _, payload := client.read()
+			if payload == nil {
+				return
+			}
+			received <- payload
+		}
+	}()
+	// the slow peer never reads, its writer blocks on the first message and its queue fills up.
+	for i := 0; i < 5; i++ {
+		hub.Broadcast("room", TextMessage, []byte{'0' + byte(i)})
+		assert.Equal(t, []byte{'0' + byte(i)}, <-received)
+	}
+	<-slow.Done()
+	assert.Equal(t, 1, hub.Len("room"))
+	hub.LeaveAll(fast)
+	assert.Equal(t, 0, hub.Len("room"))
+}